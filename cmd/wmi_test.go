@@ -0,0 +1,22 @@
+package cmd
+
+import "testing"
+
+func TestWmiRegTypeDefaults(t *testing.T) {
+  if err := wmiRegGetCmd.ParseFlags([]string{"--key", `HKLM\SOFTWARE`, "--name", "v"}); err != nil {
+    t.Fatalf("parse get flags: %v", err)
+  }
+  if wmiReg.Type != "" {
+    t.Errorf("get: got type %q, want empty so the type is determined with EnumValues", wmiReg.Type)
+  }
+
+  if err := wmiRegSetCmd.ParseFlags([]string{"--key", `HKLM\SOFTWARE`, "--data", "x"}); err != nil {
+    t.Fatalf("parse set flags: %v", err)
+  }
+  if wmiRegSetType != "SZ" {
+    t.Errorf("set: got type %q, want %q", wmiRegSetType, "SZ")
+  }
+  if wmiReg.Type != "" {
+    t.Errorf("set flags changed the get type to %q", wmiReg.Type)
+  }
+}
@@ -0,0 +1,80 @@
+package atsvcexec
+
+import (
+  "context"
+  "time"
+
+  "github.com/FalconOpsLLC/goexec/pkg/goexec"
+  "github.com/rs/zerolog"
+)
+
+const (
+  MethodJob = "Job"
+)
+
+type AtsvcJob struct {
+  Atsvc
+  goexec.Executor
+  goexec.Cleaner
+
+  IO goexec.ExecutionIO
+
+  NoDelete   bool
+  StartDelay time.Duration
+  StopDelay  time.Duration
+
+  // LocalTime schedules the job using the local clock instead of calling NetrRemoteTOD
+  LocalTime bool
+}
+
+func (m *AtsvcJob) Execute(ctx context.Context, execIO *goexec.ExecutionIO) (err error) {
+
+  log := zerolog.Ctx(ctx).With().
+    Str("module", ModuleName).
+    Str("method", MethodJob).
+    Logger()
+
+  ctx = log.WithContext(ctx)
+
+  now := time.Now()
+
+  if !m.LocalTime {
+    if remote, err := m.remoteTime(ctx); err != nil {
+      log.Warn().Err(err).Msg("Failed to fetch remote time of day, falling back to local time")
+    } else {
+      now = remote
+      log.Debug().Time("time", now).Msg("Fetched remote time of day")
+    }
+  }
+
+  jobId, err := m.addJob(ctx, execIO.String(), now.Add(m.StartDelay))
+  if err != nil {
+    return err
+  }
+
+  if !m.NoDelete {
+    m.AddCleaners(func(ctxInner context.Context) error {
+
+      log.Info().Msg("Waiting for job to start...")
+
+      select {
+      case <-ctxInner.Done():
+        log.Warn().Msg("Job deletion cancelled")
+
+      case <-time.After(m.StartDelay + m.StopDelay):
+        if job, err := m.findJob(ctxInner, jobId); err != nil {
+          log.Debug().Err(err).Msg("Failed to enumerate jobs")
+
+        } else if job == nil {
+          log.Warn().Uint32("job", jobId).Msg("Job no longer exists")
+          return nil
+
+        } else if job.Flags&JobExecError != 0 {
+          log.Warn().Uint32("job", jobId).Msg("Job execution failed")
+        }
+      }
+      return m.deleteJob(ctxInner, jobId)
+    })
+  }
+  return
+}
@@ -0,0 +1,170 @@
+package atsvcexec
+
+import (
+  "context"
+  "errors"
+  "fmt"
+  "time"
+
+  "github.com/FalconOpsLLC/goexec/pkg/goexec"
+  "github.com/FalconOpsLLC/goexec/pkg/goexec/dce"
+  "github.com/oiweiwei/go-msrpc/dcerpc"
+  "github.com/oiweiwei/go-msrpc/msrpc/srvs/srvsvc/v3"
+  "github.com/oiweiwei/go-msrpc/msrpc/tsch"
+  "github.com/oiweiwei/go-msrpc/msrpc/tsch/atsvc/v1"
+  "github.com/rs/zerolog"
+
+  _ "github.com/oiweiwei/go-msrpc/msrpc/erref/ntstatus"
+  _ "github.com/oiweiwei/go-msrpc/msrpc/erref/win32"
+)
+
+const (
+  ModuleName = "ATSVC"
+
+  DefaultEndpoint = "ncacn_np:[atsvc]"
+  SrvsvcEndpoint  = "ncacn_np:[srvsvc]"
+
+  // JobNonInteractive prevents the job from interacting with the logged-on user (JOB_NONINTERACTIVE)
+  JobNonInteractive uint8 = 0x10
+
+  // JobExecError is set by the server when the last execution of the job failed (JOB_EXEC_ERROR)
+  JobExecError uint8 = 0x02
+)
+
+type Atsvc struct {
+  goexec.Cleaner
+
+  Client *dce.Client
+  atsvc  atsvc.ATSvcClient
+}
+
+func (m *Atsvc) Connect(ctx context.Context) (err error) {
+
+  if err = m.Client.Connect(ctx); err == nil {
+    m.AddCleaners(m.Client.Close)
+  }
+  return
+}
+
+func (m *Atsvc) Init(ctx context.Context) (err error) {
+
+  if m.Client.Dce() == nil {
+    return errors.New("DCE connection not initialized")
+  }
+
+  // Create ATSvc Client
+  m.atsvc, err = atsvc.NewATSvcClient(ctx, m.Client.Dce(), dcerpc.WithSeal())
+  return
+}
+
+// remoteTime calls NetrRemoteTOD on the srvsvc pipe to fetch the current time and time zone of the target.
+// AT jobs are scheduled in the local time of the server, so the local clock can't be relied on
+func (m *Atsvc) remoteTime(ctx context.Context) (now time.Time, err error) {
+
+  srv := dce.Client{Options: m.Client.Options}
+  srv.Endpoint = SrvsvcEndpoint
+  srv.Filter = ""
+  srv.UseEpm = false
+
+  if err = srv.Parse(ctx); err != nil {
+    return now, fmt.Errorf("parse srvsvc client: %w", err)
+  }
+  if err = srv.Connect(ctx); err != nil {
+    return now, err
+  }
+  defer func() {
+    if closeErr := srv.Close(ctx); closeErr != nil {
+      zerolog.Ctx(ctx).Debug().Err(closeErr).Msg("Failed to close srvsvc connection")
+    }
+  }()
+
+  srvs, err := srvsvc.NewSrvsvcClient(ctx, srv.Dce(), dcerpc.WithSeal())
+  if err != nil {
+    return now, fmt.Errorf("create srvsvc client: %w", err)
+  }
+
+  todResponse, err := srvs.RemoteToD(ctx, &srvsvc.RemoteToDRequest{})
+  if err != nil {
+    return now, fmt.Errorf("remote TOD: %w", err)
+  }
+  tod := todResponse.BufferPointer
+
+  if tod == nil {
+    return now, errors.New("remote TOD returned no time of day information")
+  }
+
+  now = time.Unix(int64(tod.ElapsedTime), int64(tod.Hunds)*int64(10*time.Millisecond)).UTC()
+
+  // Timezone is the number of minutes the server is behind UTC, or -1 if undefined
+  if tod.Timezone != -1 {
+    now = now.In(time.FixedZone("", -int(tod.Timezone)*60))
+  }
+  return
+}
+
+// addJob calls NetrJobAdd to schedule a one-shot job running the provided command at the specified local time of the server
+func (m *Atsvc) addJob(ctx context.Context, command string, at time.Time) (jobId uint32, err error) {
+
+  log := zerolog.Ctx(ctx)
+
+  h, min, s := at.Clock()
+
+  addResponse, err := m.atsvc.JobAdd(ctx, &atsvc.JobAddRequest{
+    ATInfo: &tsch.ATInfo{
+      JobTime: uint64((h*3600 + min*60 + s) * 1000), // Milliseconds since midnight
+      Flags:   JobNonInteractive,
+      Command: command,
+    },
+  })
+
+  if err != nil {
+    log.Error().Err(err).Msg("Failed to add job")
+    return 0, fmt.Errorf("add job: %w", err)
+  }
+
+  log.Info().Uint32("job", addResponse.JobID).Str("at", at.Format(time.TimeOnly)).Msg("Job added")
+
+  return addResponse.JobID, nil
+}
+
+// deleteJob calls NetrJobDel to remove a single job
+func (m *Atsvc) deleteJob(ctx context.Context, jobId uint32) (err error) {
+
+  log := zerolog.Ctx(ctx).With().
+    Uint32("job", jobId).Logger()
+
+  _, err = m.atsvc.JobDelete(ctx, &atsvc.JobDeleteRequest{
+    MinJobID: jobId,
+    MaxJobID: jobId,
+  })
+
+  if err != nil {
+    log.Error().Err(err).Msg("Failed to delete job")
+    return fmt.Errorf("delete job: %w", err)
+  }
+
+  log.Info().Msg("Job deleted")
+  return
+}
+
+// findJob calls NetrJobEnum and returns the job with the provided ID, or nil if it no longer exists.
+// All jobs are returned in a single call, as the preferred maximum length is unlimited
+func (m *Atsvc) findJob(ctx context.Context, jobId uint32) (job *tsch.ATEnum, err error) {
+
+  enumResponse, err := m.atsvc.JobEnum(ctx, &atsvc.JobEnumRequest{
+    EnumContainer:          &atsvc.ATEnumContainer{},
+    PreferredMaximumLength: 0xffffffff,
+  })
+
+  if err != nil {
+    return nil, fmt.Errorf("enumerate jobs: %w", err)
+  }
+  if enumResponse.EnumContainer != nil {
+    for _, j := range enumResponse.EnumContainer.Buffer {
+      if j != nil && j.JobID == jobId {
+        return j, nil
+      }
+    }
+  }
+  return nil, nil
+}
@@ -2,6 +2,7 @@ package dcomexec
 
 import (
   "context"
+  "errors"
   "fmt"
 
   "github.com/oiweiwei/go-msrpc/dcerpc"
@@ -23,56 +24,88 @@ const (
 
 // remoteCreateInstance creates a new instance of a COM class on a remote machine using RemoteCreateInstance (opnum 4).
 func (m *Dcom) remoteCreateInstance(ctx context.Context, conn dcerpc.Conn, cls *uuid.UUID, iid *dcom.IID) (opts []dcerpc.Option, err error) {
+  reply, out, err := m.createInstance(ctx, conn, cls, iid)
+  if err != nil {
+    return nil, err
+  }
+  m.oxid, m.remUnknown = reply.OXID, reply.IPIDRemoteUnknown
+
+  if len(out.HResults) > 0 && out.HResults[0] != 0 {
+    return nil, fmt.Errorf("remote create instance: %w", hresult.FromCode(uint32(out.HResults[0])))
+  }
+  if opts, err = m.bindingOptions(ctx, reply.OXIDBindings.GetStringBindings()); err != nil {
+    return nil, err
+  }
+  if len(out.InterfaceData) > 0 && out.InterfaceData[0] != nil {
+    opts = append(opts, dcom.WithIPID(out.InterfaceData[0].GetStandardObjectReference().Std.IPID))
+    m.track(out.InterfaceData[0])
+  } else {
+    return nil, fmt.Errorf("remote create instance response: PropertiesOutInfo is nil")
+  }
+  return opts, err
+}
+
+// isClassNotRegistered determines whether err reports that the class isn't registered on the remote host
+func isClassNotRegistered(err error) bool {
+  var he *hresult.Error
+  return errors.As(err, &he) && he.Code == hresult.RegdbEClassnotreg.Code
+}
+
+// createInstance calls RemoteCreateInstance to create an instance of a COM class, requesting each of the provided interfaces.
+// The result of each interface request is returned in the PropertiesOutInfo, in the same order as iids
+func (m *Dcom) createInstance(ctx context.Context, conn dcerpc.Conn, cls *uuid.UUID, iids ...*dcom.IID) (reply *dcom.CustomRemoteReplySCMInfo, out *dcom.PropertiesOutInfo, err error) {
   if cls == nil {
-    return nil, fmt.Errorf("class ID is nil")
+    return nil, nil, fmt.Errorf("class ID is nil")
+  }
+  seqs, err := m.protocolSequences(ctx)
+  if err != nil {
+    return nil, nil, err
   }
   ap := &dcom.ActivationProperties{
     DestinationContext: 2,
     Properties: []dcom.ActivationProperty{
       &dcom.InstantiationInfoData{
         ClassID:          (*dcom.ClassID)(dtyp.GUIDFromUUID(cls)),
-        IID:              []*dcom.IID{iid},
+        IID:              iids,
         ClientCOMVersion: m.comVersion,
       },
       &dcom.ActivationContextInfoData{},
       &dcom.LocationInfoData{},
       &dcom.SCMRequestInfoData{
         RemoteRequest: &dcom.CustomRemoteRequestSCMInfo{
-          RequestedProtocolSequences: []uint16{7, 15}, // ncacn_ip_tcp, ncacn_np
+          RequestedProtocolSequences: seqs,
         },
       },
     },
   }
   apin, err := ap.ActivationPropertiesIn()
   if err != nil {
-    return nil, err
+    return nil, nil, err
   }
   act, err := iremotescmactivator.NewRemoteSCMActivatorClient(ctx, conn)
   if err != nil {
-    return nil, err
+    return nil, nil, err
   }
   cr, err := act.RemoteCreateInstance(ctx, &iremotescmactivator.RemoteCreateInstanceRequest{
     ORPCThis:        &dcom.ORPCThis{Version: m.comVersion},
     ActPropertiesIn: apin,
   })
   if err != nil {
-    return nil, err
+    return nil, nil, err
   }
   apout := new(dcom.ActivationProperties)
   if err = apout.Parse(cr.ActPropertiesOut); err != nil {
-    return nil, err
+    return nil, nil, err
   }
-  if si := apout.SCMReplyInfoData(); si != nil {
-    opts = append(opts, normalizeStringBindings(si.RemoteReply.OXIDBindings.GetStringBindings())...)
+  if si := apout.SCMReplyInfoData(); si != nil && si.RemoteReply != nil {
+    reply = si.RemoteReply
   } else {
-    return nil, fmt.Errorf("remote create instance response: SCMReplyInfoData is nil")
+    return nil, nil, fmt.Errorf("remote create instance response: SCMReplyInfoData is nil")
   }
-  if pi := apout.PropertiesOutInfo(); pi != nil && pi.InterfaceData != nil && len(pi.InterfaceData) > 0 {
-    opts = append(opts, dcom.WithIPID(pi.InterfaceData[0].GetStandardObjectReference().Std.IPID))
-  } else {
-    return nil, fmt.Errorf("remote create instance response: PropertiesOutInfo is nil")
+  if out = apout.PropertiesOutInfo(); out == nil {
+    return nil, nil, fmt.Errorf("remote create instance response: PropertiesOutInfo is nil")
   }
-  return opts, err
+  return
 }
 
 // remoteActivation activates a COM class on a remote machine using RemoteActivation (opnum 0).
@@ -80,6 +113,10 @@ func (m *Dcom) remoteActivation(ctx context.Context, conn dcerpc.Conn, cls *uuid
   if cls == nil {
     return nil, fmt.Errorf("class ID is nil")
   }
+  seqs, err := m.protocolSequences(ctx)
+  if err != nil {
+    return nil, err
+  }
   ac, err := iactivation.NewActivationClient(ctx, conn)
   if err != nil {
     return nil, fmt.Errorf("init activation client: %w", err)
@@ -88,7 +125,7 @@ func (m *Dcom) remoteActivation(ctx context.Context, conn dcerpc.Conn, cls *uuid
     ORPCThis:                   &dcom.ORPCThis{Version: m.comVersion},
     ClassID:                    dtyp.GUIDFromUUID(cls),
     IIDs:                       []*dcom.IID{iid},
-    RequestedProtocolSequences: []uint16{7, 15}, // ncacn_ip_tcp, ncacn_np
+    RequestedProtocolSequences: seqs,
   })
   if err != nil {
     return nil, fmt.Errorf("remote activation: %w", err)
@@ -96,6 +133,11 @@ func (m *Dcom) remoteActivation(ctx context.Context, conn dcerpc.Conn, cls *uuid
   if act.HResult != 0 {
     return nil, hresult.FromCode(uint32(act.HResult))
   }
-  return append(normalizeStringBindings(act.OXIDBindings.GetStringBindings()),
-    dcom.WithIPID(act.InterfaceData[0].GetStandardObjectReference().Std.IPID)), nil
+  m.oxid, m.remUnknown = act.OXID, act.RemoteUnknown
+  m.track(act.InterfaceData[0])
+
+  if opts, err = m.bindingOptions(ctx, act.OXIDBindings.GetStringBindings()); err != nil {
+    return nil, err
+  }
+  return append(opts, dcom.WithIPID(act.InterfaceData[0].GetStandardObjectReference().Std.IPID)), nil
 }
@@ -0,0 +1,105 @@
+package dcomexec
+
+import (
+  "context"
+  "fmt"
+  "strings"
+
+  "github.com/oiweiwei/go-msrpc/dcerpc"
+  "github.com/oiweiwei/go-msrpc/msrpc/dcom"
+  "github.com/rs/zerolog"
+)
+
+const (
+  TransportAuto = "auto" // Prefer the transport of the object resolver connection, or use the other if it isn't offered
+  TransportTCP  = "tcp"  // ncacn_ip_tcp
+  TransportNP   = "np"   // ncacn_np
+
+  // NamedPipeEndpoint is the object resolver endpoint reachable over SMB
+  NamedPipeEndpoint = "ncacn_np:[epmapper]"
+
+  protocolSequenceTCP uint16 = 7  // ncacn_ip_tcp
+  protocolSequenceNP  uint16 = 15 // ncacn_np
+)
+
+// Transports are the accepted values of WithTransport
+var Transports = []string{TransportAuto, TransportTCP, TransportNP}
+
+// protocolSequences returns the protocol sequences to request during activation, in order of preference
+func (m *Dcom) protocolSequences(ctx context.Context) ([]uint16, error) {
+  switch t := contextTransport(ctx); t {
+  case TransportTCP:
+    return []uint16{protocolSequenceTCP}, nil
+  case TransportNP:
+    return []uint16{protocolSequenceNP}, nil
+  case TransportAuto:
+    if m.Client != nil && m.Client.Smb {
+      // The object resolver was reached over SMB, so TCP may not be reachable at all
+      return []uint16{protocolSequenceNP, protocolSequenceTCP}, nil
+    }
+    return []uint16{protocolSequenceTCP, protocolSequenceNP}, nil
+  default:
+    return nil, fmt.Errorf("unsupported DCOM transport %q", t)
+  }
+}
+
+// selectBindings returns endpoint options for the OXID bindings of the first preferred protocol sequence offered
+// by the object exporter. The address/hostname is removed from each binding to prevent name resolution issues,
+// and smb reports whether named pipe bindings were selected, in which case the connection requires the SMB dialer
+func (m *Dcom) selectBindings(ctx context.Context, bindings []*dcom.StringBinding) (opts []dcerpc.Option, smb bool, err error) {
+  seqs, err := m.protocolSequences(ctx)
+  if err != nil {
+    return nil, false, err
+  }
+  seen := make(map[string]bool)
+
+  for _, seq := range seqs {
+    for _, b := range bindings {
+      if b.TowerID != seq {
+        continue
+      }
+      s, err := dcerpc.ParseStringBinding(b.String())
+      if err != nil || s.Endpoint == "" {
+        continue
+      }
+      s.NetworkAddress = ""
+
+      if e := s.String(); !seen[e] {
+        seen[e] = true
+        opts = append(opts, dcerpc.WithEndpoint(e))
+      }
+    }
+    if len(opts) > 0 {
+      // Bindings of a single transport are used, so that the SMB dialer is only enabled for named pipes
+      return opts, seq == protocolSequenceNP, nil
+    }
+  }
+  return nil, false, fmt.Errorf("object exporter offered no usable binding for transport %q (offered: %s)",
+    contextTransport(ctx), describeBindings(bindings))
+}
+
+// bindingOptions selects the OXID bindings used to reconnect to the object exporter, and enables
+// the SMB dialer on the client if a named pipe binding was selected
+func (m *Dcom) bindingOptions(ctx context.Context, bindings []*dcom.StringBinding) (opts []dcerpc.Option, err error) {
+  opts, smb, err := m.selectBindings(ctx, bindings)
+  if err != nil {
+    return nil, err
+  }
+  if smb && !m.Client.Smb {
+    zerolog.Ctx(ctx).Debug().Msg("Using SMB transport for named pipe OXID bindings")
+    m.Client.Smb = true
+  }
+  return
+}
+
+// describeBindings joins the string bindings for error messages
+func describeBindings(bindings []*dcom.StringBinding) string {
+  if len(bindings) == 0 {
+    return "none"
+  }
+  s := make([]string, 0, len(bindings))
+  for _, b := range bindings {
+    s = append(s, b.String())
+  }
+  return strings.Join(s, ", ")
+}
@@ -2,6 +2,9 @@ package dcomexec
 
 import (
   "context"
+  "fmt"
+  "strconv"
+  "strings"
 
   "github.com/oiweiwei/go-msrpc/msrpc/dcom"
 )
@@ -19,8 +22,13 @@ const (
   contextKeyGetComVersion     contextKey = "GetComVersion"
   contextDefaultGetComVersion            = true
 
+  // contextKeyCreateInstanceMethod (string) determines how objects are activated (OptRemoteCreateInstance or OptRemoteActivation)
   contextKeyCreateInstanceMethod     contextKey = "CreateInstanceMethod"
   contextDefaultCreateInstanceMethod            = OptRemoteCreateInstance
+
+  // contextKeyTransport (string) determines which OXID bindings are requested and used (TransportAuto, TransportTCP or TransportNP)
+  contextKeyTransport     contextKey = "Transport"
+  contextDefaultTransport            = TransportAuto
 )
 
 func contextGetComVersion(ctx context.Context) bool {
@@ -57,3 +65,53 @@ func contextCreateInstanceMethod(ctx context.Context) string {
   }
   return contextDefaultCreateInstanceMethod
 }
+
+func contextTransport(ctx context.Context) string {
+  if v := ctx.Value(contextKeyTransport); v != nil {
+    if g, ok := v.(string); ok {
+      return g
+    }
+  }
+  return contextDefaultTransport
+}
+
+// WithCreateInstanceMethod returns a copy of ctx that activates objects with the provided method,
+// either OptRemoteCreateInstance (IRemoteSCMActivator) or OptRemoteActivation (IActivation)
+func WithCreateInstanceMethod(ctx context.Context, method string) context.Context {
+  return context.WithValue(ctx, contextKeyCreateInstanceMethod, method)
+}
+
+// WithTransport returns a copy of ctx that connects to activated objects with the provided
+// transport, either TransportAuto, TransportTCP (ncacn_ip_tcp) or TransportNP (ncacn_np)
+func WithTransport(ctx context.Context, transport string) context.Context {
+  return context.WithValue(ctx, contextKeyTransport, transport)
+}
+
+// WithComVersion returns a copy of ctx that uses the provided COM version instead of
+// calling IObjectExporter.ServerAlive2
+func WithComVersion(ctx context.Context, ver dcom.COMVersion) context.Context {
+  return context.WithValue(ctx, contextKeyComVersion, ver)
+}
+
+// WithGetComVersion returns a copy of ctx that determines whether IObjectExporter.ServerAlive2 is
+// called when no COM version is set. If get is false, COM version 5.7 is assumed
+func WithGetComVersion(ctx context.Context, get bool) context.Context {
+  return context.WithValue(ctx, contextKeyGetComVersion, get)
+}
+
+// ParseComVersion parses a COM version in the form "major.minor" (i.e. 5.7)
+func ParseComVersion(s string) (ver dcom.COMVersion, err error) {
+  major, minor, ok := strings.Cut(s, ".")
+  if !ok {
+    return ver, fmt.Errorf("invalid COM version %q: expected major.minor", s)
+  }
+  mj, err := strconv.ParseUint(major, 10, 16)
+  if err != nil {
+    return ver, fmt.Errorf("invalid COM version %q: %w", s, err)
+  }
+  mn, err := strconv.ParseUint(minor, 10, 16)
+  if err != nil {
+    return ver, fmt.Errorf("invalid COM version %q: %w", s, err)
+  }
+  return dcom.COMVersion{MajorVersion: uint16(mj), MinorVersion: uint16(mn)}, nil
+}
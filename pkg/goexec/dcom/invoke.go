@@ -0,0 +1,144 @@
+package dcomexec
+
+import (
+  "context"
+  "encoding/json"
+  "fmt"
+  "io"
+  "strings"
+
+  "github.com/oiweiwei/go-msrpc/midl/uuid"
+  "github.com/oiweiwei/go-msrpc/msrpc/dcom/oaut"
+  "github.com/oiweiwei/go-msrpc/msrpc/erref/hresult"
+  "github.com/rs/zerolog"
+)
+
+const (
+  MethodDispatch = "Dispatch" // <CLSID>::<Member>
+
+  InvokeCall = "call"
+  InvokeGet  = "get"
+  InvokePut  = "put"
+)
+
+// DcomDispatch invokes an arbitrary member of a DCOM object through IDispatch
+type DcomDispatch struct {
+  Dispatch
+
+  // Clsid is the class ID of the object to instantiate
+  Clsid string
+
+  // Member is the dot-separated member to invoke (i.e. Document.ActiveView.ExecuteShellCommand)
+  Member string
+
+  // Invoke is the kind of invocation, either InvokeCall (default), InvokeGet or InvokePut
+  Invoke string
+
+  // Args are the typed arguments of the member, in declaration order (see parseVariant).
+  // Arguments prefixed with VariantGetPrefix are the result of a property get on the object
+  Args []string
+
+  Out io.Writer
+}
+
+type dispatchResult struct {
+  Member string `json:"member"`
+  Return int32  `json:"return"`
+  VT     uint16 `json:"vt"`
+  Result any    `json:"result"`
+}
+
+// Init will initialize the IDispatch instance of Clsid
+func (m *DcomDispatch) Init(ctx context.Context) (err error) {
+  cls, err := uuid.Parse(m.Clsid)
+  if err != nil {
+    return fmt.Errorf("parse CLSID: %w", err)
+  }
+  if err = m.Dcom.Init(ctx); err == nil {
+    return m.getDispatch(ctx, cls)
+  }
+  return
+}
+
+func (m *DcomDispatch) Call(ctx context.Context) (err error) {
+  log := zerolog.Ctx(ctx).With().
+    Str("clsid", m.Clsid).
+    Str("member", m.Member).
+    Logger()
+
+  var flags uint32
+
+  switch strings.ToLower(m.Invoke) {
+  case InvokeCall, "":
+    flags = DispatchMethod
+  case InvokeGet:
+    flags = DispatchPropertyGet
+  case InvokePut:
+    if len(m.Args) != 1 {
+      return fmt.Errorf("property put requires exactly one value, got %d", len(m.Args))
+    }
+    flags = DispatchPropertyPut
+  default:
+    return fmt.Errorf("invalid invocation %q", m.Invoke)
+  }
+
+  // Arguments must be passed in reverse order
+  args := make([]*oaut.Variant, len(m.Args))
+
+  for i, arg := range m.Args {
+    v, err := m.argument(ctx, arg)
+    if err != nil {
+      return fmt.Errorf("argument %d: %w", i+1, err)
+    }
+    if v.VT == vtDispatch && flags == DispatchPropertyPut {
+      flags = DispatchPropertyPutRef // Object properties are assigned by reference
+    }
+    args[len(args)-1-i] = v
+  }
+
+  log.Info().Str("invoke", m.Invoke).Int("args", len(args)).Msg("Invoking member")
+
+  ir, err := m.invoke(ctx, nil, m.Member, flags, args...)
+  if err != nil {
+    return err
+  }
+  if ir.Return != 0 {
+    return fmt.Errorf("invoke %q: %w", m.Member, hresult.FromCode(uint32(ir.Return)))
+  }
+  res := dispatchResult{Member: m.Member, Return: ir.Return}
+
+  if ir.VarResult != nil {
+    res.VT, res.Result = ir.VarResult.VT, variantValue(ir.VarResult)
+  }
+  log.Info().Uint16("vt", res.VT).Msg("Invoke successful")
+
+  if m.Out != nil {
+    out, err := json.Marshal(res)
+    if err != nil {
+      return fmt.Errorf("marshal output: %w", err)
+    }
+    if _, err = m.Out.Write(append(out, 0x0a)); err != nil {
+      return fmt.Errorf("write output: %w", err)
+    }
+  }
+  return
+}
+
+// argument converts a typed argument to a *oaut.Variant. Property gets (VariantGetPrefix) are resolved on the object
+func (m *DcomDispatch) argument(ctx context.Context, arg string) (*oaut.Variant, error) {
+  path, ok := strings.CutPrefix(arg, VariantGetPrefix)
+  if !ok {
+    return parseVariant(arg)
+  }
+  ir, err := m.invoke(ctx, nil, path, DispatchPropertyGet)
+  if err != nil {
+    return nil, err
+  }
+  if ir.Return != 0 {
+    return nil, fmt.Errorf("get %q: %w", path, hresult.FromCode(uint32(ir.Return)))
+  }
+  if ir.VarResult == nil {
+    return nil, fmt.Errorf("get %q: no value returned", path)
+  }
+  return ir.VarResult, nil
+}
@@ -0,0 +1,179 @@
+package dcomexec
+
+import (
+  "context"
+  "errors"
+  "fmt"
+  "sync"
+  "syscall"
+  "time"
+
+  "github.com/oiweiwei/go-msrpc/dcerpc"
+  "github.com/oiweiwei/go-msrpc/msrpc/dcom"
+  "github.com/oiweiwei/go-msrpc/msrpc/dcom/iobjectexporter/v0"
+  "github.com/oiweiwei/go-msrpc/msrpc/dcom/iremunknown/v0"
+  "github.com/oiweiwei/go-msrpc/msrpc/dcom/iremunknown2/v0"
+  "github.com/oiweiwei/go-msrpc/msrpc/erref/hresult"
+  "github.com/rs/zerolog"
+)
+
+const (
+  // PingPeriod is the interval between pings of the ping set. The server may garbage-collect
+  // objects that haven't been pinged for three ping periods (120 seconds each by default)
+  PingPeriod = 2 * time.Minute
+
+  // sorfNoPing is set on object references that don't need to be pinged
+  sorfNoPing uint32 = 0x1000
+)
+
+// pingSet keeps the objects acquired from the object exporter alive with IObjectExporter pings
+type pingSet struct {
+  mu    sync.Mutex
+  setID uint64
+  seq   uint16
+  add   []uint64 // OIDs not yet added to the set
+
+  stop context.CancelFunc
+  done chan struct{}
+}
+
+// track records an acquired interface pointer. The object is added to the ping set unless it doesn't
+// need to be pinged, and references to objects of the object exporter are released in Clean
+func (m *Dcom) track(ptr *dcom.InterfacePointer) {
+  if ptr == nil {
+    return
+  }
+  std := ptr.GetStandardObjectReference().Std
+  if std == nil || std.IPID == nil {
+    return
+  }
+  if std.Flags&sorfNoPing == 0 {
+    m.ping.mu.Lock()
+    m.ping.add = append(m.ping.add, std.OID)
+    m.ping.mu.Unlock()
+  }
+  if std.PublicReferencesCount == 0 || std.OXID != m.oxid {
+    return // Objects of other exporters (i.e. explorer.exe) can't be released through remUnknown
+  }
+  id := std.IPID.UUID().String()
+
+  for _, ref := range m.refs {
+    if ref.IPID.UUID().String() == id {
+      ref.PublicReferencesCount += std.PublicReferencesCount
+      return
+    }
+  }
+  m.refs = append(m.refs, &dcom.RemoteInterfaceReference{
+    IPID:                  std.IPID,
+    PublicReferencesCount: std.PublicReferencesCount,
+  })
+}
+
+// release releases the references of all tracked interface pointers with IRemUnknown2::RemRelease
+func (m *Dcom) release(ctx context.Context) (err error) {
+  if len(m.refs) == 0 || m.remUnknown == nil {
+    return
+  }
+  refs := m.refs
+  m.refs = nil
+
+  ru, err := iremunknown2.NewRemoteUnknown2Client(ctx, m.Client.Dce(), dcom.WithIPID(m.remUnknown))
+  if err != nil {
+    return fmt.Errorf("init IRemUnknown2 client: %w", err)
+  }
+  rr, err := ru.RemoteUnknown().RemoteRelease(ctx, &iremunknown.RemoteReleaseRequest{
+    This:                &dcom.ORPCThis{Version: m.comVersion},
+    InterfaceReferences: refs,
+  })
+  if err != nil {
+    return fmt.Errorf("release %d interface(s): %w", len(refs), err)
+  }
+  if rr.Return != 0 {
+    return fmt.Errorf("release %d interface(s): %w", len(refs), hresult.FromCode(uint32(rr.Return)))
+  }
+  zerolog.Ctx(ctx).Debug().Int("interfaces", len(refs)).Msg("Released remote interfaces")
+  return
+}
+
+// startPing pings the tracked objects every PingPeriod through the object resolver until stopPing is called
+func (m *Dcom) startPing(ctx context.Context, conn dcerpc.Conn) (err error) {
+  if m.ping.stop != nil {
+    return
+  }
+  oe, err := iobjectexporter.NewObjectExporterClient(ctx, conn)
+  if err != nil {
+    return fmt.Errorf("init IObjectExporter client: %w", err)
+  }
+  ctx, m.ping.stop = context.WithCancel(ctx)
+  m.ping.done = make(chan struct{})
+
+  go func() {
+    log := zerolog.Ctx(ctx)
+    tick := time.NewTicker(PingPeriod)
+
+    defer close(m.ping.done)
+    defer tick.Stop()
+
+    for {
+      select {
+      case <-ctx.Done():
+        return
+      case <-tick.C:
+        if err := m.pingOnce(ctx, oe); err != nil {
+          log.Warn().Err(err).Msg("Failed to ping remote objects")
+        }
+      }
+    }
+  }()
+  return
+}
+
+// pingOnce adds new objects to the ping set with ComplexPing, or pings the set with SimplePing
+func (m *Dcom) pingOnce(ctx context.Context, oe iobjectexporter.ObjectExporterClient) error {
+  m.ping.mu.Lock()
+  defer m.ping.mu.Unlock()
+
+  if len(m.ping.add) == 0 && m.ping.setID == 0 {
+    return nil // Nothing to ping
+  }
+  if len(m.ping.add) > 0 {
+    m.ping.seq++
+    cr, err := oe.ComplexPing(ctx, &iobjectexporter.ComplexPingRequest{
+      SetID:       m.ping.setID,
+      SequenceNum: m.ping.seq,
+      AddToSet:    m.ping.add,
+    })
+    if err != nil {
+      return fmt.Errorf("complex ping: %w", err)
+    }
+    m.ping.setID, m.ping.add = cr.SetID, nil
+    zerolog.Ctx(ctx).Debug().Uint64("set", m.ping.setID).Msg("Updated ping set")
+    return nil
+  }
+  if _, err := oe.SimplePing(ctx, &iobjectexporter.SimplePingRequest{SetID: m.ping.setID}); err != nil {
+    return fmt.Errorf("simple ping: %w", err)
+  }
+  return nil
+}
+
+// stopPing stops pinging the tracked objects
+func (m *Dcom) stopPing() {
+  if m.ping.stop != nil {
+    m.ping.stop()
+    <-m.ping.done
+    m.ping.stop = nil
+  }
+}
+
+// Clean stops pinging and releases the tracked interface pointers before running the cleaners,
+// which close the connection to the object exporter
+func (m *Dcom) Clean(ctx context.Context) (err error) {
+  m.stopPing()
+
+  if err = m.release(ctx); errors.Is(err, syscall.ECONNRESET) {
+    zerolog.Ctx(ctx).Debug().Err(err).Msg("Object exporter closed the connection before release")
+  } else if err != nil {
+    zerolog.Ctx(ctx).Warn().Err(err).Msg("Failed to release remote objects")
+  }
+  return m.Cleaner.Clean(ctx)
+}
@@ -9,6 +9,7 @@ import (
   "github.com/oiweiwei/go-msrpc/dcerpc"
   "github.com/oiweiwei/go-msrpc/midl/uuid"
   "github.com/oiweiwei/go-msrpc/msrpc/dcom"
+  "github.com/rs/zerolog"
 
   _ "github.com/oiweiwei/go-msrpc/msrpc/erref/hresult"
   _ "github.com/oiweiwei/go-msrpc/msrpc/erref/ntstatus"
@@ -25,6 +26,16 @@ type Dcom struct {
 
   Client     *dce.Client
   comVersion *dcom.COMVersion
+
+  // resolver is the connection to the object resolver used for activation and pinging
+  resolver dcerpc.Conn
+  // oxid identifies the object exporter of the activated object
+  oxid uint64
+  // remUnknown is the IPID of the IRemUnknown interface of the object exporter
+  remUnknown *dcom.IPID
+  // refs are the acquired interface pointers, released in Clean
+  refs []*dcom.RemoteInterfaceReference
+  ping pingSet
 }
 
 func (m *Dcom) Connect(ctx context.Context) (err error) {
@@ -45,15 +56,32 @@ func (m *Dcom) Init(ctx context.Context) (err error) {
 }
 
 func (m *Dcom) bindInstance(ctx context.Context, cls *uuid.UUID, iid *dcom.IID) (opts []dcerpc.Option, err error) {
+  first, resolver := m.resolver == nil, m.resolver
+  if first {
+    resolver = m.Client.Dce()
+  }
   if mt := contextCreateInstanceMethod(ctx); mt == OptRemoteCreateInstance {
-    opts, err = m.remoteCreateInstance(ctx, m.Client.Dce(), cls, iid)
+    opts, err = m.remoteCreateInstance(ctx, resolver, cls, iid)
   } else if mt == OptRemoteActivation {
-    opts, err = m.remoteActivation(ctx, m.Client.Dce(), cls, iid)
+    opts, err = m.remoteActivation(ctx, resolver, cls, iid)
   } else {
     return nil, fmt.Errorf("invalid create instance method: %s", mt)
   }
   if err != nil {
     return nil, fmt.Errorf("create instance: %w", err)
   }
-  return opts, m.Client.Reconnect(ctx, opts...)
+  if err = m.Client.Reconnect(ctx, opts...); err != nil {
+    return
+  }
+  if first {
+    // The object resolver connection is replaced by the object exporter connection
+    m.resolver = resolver
+    m.AddCleaners(m.resolver.Close)
+
+    if err = m.startPing(ctx, m.resolver); err != nil {
+      zerolog.Ctx(ctx).Warn().Err(err).Msg("Failed to start pinging remote objects")
+      err = nil
+    }
+  }
+  return
 }
@@ -0,0 +1,101 @@
+package dcomexec
+
+import (
+  "context"
+  "errors"
+  "fmt"
+  "syscall"
+
+  "github.com/FalconOpsLLC/goexec/pkg/goexec"
+  "github.com/oiweiwei/go-msrpc/midl/uuid"
+  "github.com/oiweiwei/go-msrpc/msrpc/dcom/oaut"
+  "github.com/oiweiwei/go-msrpc/msrpc/erref/hresult"
+  "github.com/rs/zerolog"
+)
+
+const (
+  MethodOutlook          = "Outlook.Application" // Outlook.Application::CreateObject("WScript.Shell").Run
+  OutlookApplicationUuid = "0006F03A-0000-0000-C000-000000000046"
+)
+
+type DcomOutlook struct {
+  Dispatch
+
+  // WindowStyle is the intWindowStyle argument of WScript.Shell.Run (0 hides the window)
+  WindowStyle int32
+
+  NoTerminate bool
+}
+
+// Init will initialize the Outlook.Application instance
+func (m *DcomOutlook) Init(ctx context.Context) (err error) {
+  if err = m.Dcom.Init(ctx); err == nil {
+    return m.getDispatch(ctx, uuid.MustParse(OutlookApplicationUuid))
+  }
+  return
+}
+
+// quit will terminate OUTLOOK.EXE via Quit()
+func (m *DcomOutlook) quit(ctx context.Context) (err error) {
+  log := zerolog.Ctx(ctx)
+  log.Info().
+    Str("call", "Quit").
+    Msg("terminating Outlook process")
+
+  qr, err := m.callComMethod(ctx, nil, "Quit")
+  if err != nil {
+    if errors.Is(err, syscall.ECONNRESET) {
+      log.Info().Msg("Outlook process terminated")
+      return nil
+    }
+    log.Warn().Err(err).Msg("Call Quit failed")
+    return
+  }
+  if qr.Return != 0 {
+    err = hresult.FromCode(uint32(qr.Return))
+    log.Warn().Err(err).Msgf("Call Quit: %d", qr.Return)
+  }
+  return
+}
+
+// Execute will spawn a process with the WScript.Shell object created by Outlook.Application.CreateObject
+func (m *DcomOutlook) Execute(ctx context.Context, execIO *goexec.ExecutionIO) (err error) {
+  log := zerolog.Ctx(ctx)
+
+  if !m.NoTerminate { // Terminate OUTLOOK.EXE via Quit()
+    defer func() {
+      _ = m.quit(ctx)
+    }()
+  }
+  log.Info().
+    Str("call", "CreateObject").
+    Str("class", "WScript.Shell").
+    Msg("creating shell object")
+
+  cr, err := m.callComMethod(ctx, nil, "CreateObject", stringToVariant("WScript.Shell"))
+  if err != nil {
+    return fmt.Errorf("call CreateObject: %w", err)
+  }
+  if cr.Return != 0 {
+    return fmt.Errorf("call CreateObject: %w", hresult.FromCode(uint32(cr.Return)))
+  }
+  shell, ok := cr.VarResult.VarUnion.GetValue().(*oaut.Dispatch)
+  if !ok {
+    return errors.New("call CreateObject: invalid dispatch object for WScript.Shell")
+  }
+
+  // Arguments must be passed in reverse order
+  rr, err := m.callComMethod(ctx, shell.InterfacePointer().GetStandardObjectReference().Std.IPID, "Run",
+    newVariant(vtBool, &oaut.Variant_VarUnion{Value: &oaut.Variant_VarUnion_Bool{Bool: variantBool(false)}}),
+    newVariant(vtI4, &oaut.Variant_VarUnion{Value: &oaut.Variant_VarUnion_Long{Long: m.WindowStyle}}),
+    stringToVariant(execIO.String()))
+
+  if err != nil {
+    return fmt.Errorf("call Run: %w", err)
+  }
+  if rr.Return != 0 {
+    return fmt.Errorf("call Run: %w", hresult.FromCode(uint32(rr.Return)))
+  }
+  log.Info().Msg("Method call successful")
+  return
+}
@@ -0,0 +1,280 @@
+package dcomexec
+
+import (
+  "context"
+  "encoding/json"
+  "fmt"
+  "io"
+  "slices"
+  "strings"
+  "text/tabwriter"
+
+  "github.com/oiweiwei/go-msrpc/dcerpc"
+  "github.com/oiweiwei/go-msrpc/midl/uuid"
+  "github.com/oiweiwei/go-msrpc/msrpc/dcom"
+  "github.com/oiweiwei/go-msrpc/msrpc/dcom/iunknown/v0"
+  "github.com/oiweiwei/go-msrpc/msrpc/dcom/oaut/idispatch/v0"
+  "github.com/oiweiwei/go-msrpc/msrpc/dcom/urlmon/ipersistmoniker/v0"
+  "github.com/rs/zerolog"
+)
+
+const (
+  MethodScan = "Scan"
+
+  ScanFormatJson  = "json"
+  ScanFormatTable = "table"
+)
+
+// ScanClass is a DCOM class to scan
+type ScanClass struct {
+  Name  string
+  Clsid string
+}
+
+var (
+  // KnownClasses are the classes used by the module's execution methods
+  KnownClasses = []ScanClass{
+    {"MMC20.Application", MmcUuid},
+    {"ShellWindows", ShellWindowsUuid},
+    {"ShellBrowserWindow", ShellBrowserWindowUuid},
+    {"Shell.Application", ShellApplicationUuid},
+    {"InternetExplorer.Application", InternetExplorerUuid},
+    {"HTAFile", HtafileUuid},
+    {"Excel.Application", ExcelApplicationUuid},
+    {"Outlook.Application", OutlookApplicationUuid},
+    {"Word.Application", WordApplicationUuid},
+    {"VisualStudio.DTE", VisualStudioDteUuid},
+    {"VisualStudio.DTE (2019)", VisualStudioDte2019Uuid},
+  }
+
+  // scanInterfaces are the interfaces requested from each class
+  scanInterfaces = []struct {
+    name string
+    iid  *dcom.IID
+  }{
+    {"IUnknown", iunknown.UnknownIID},
+    {"IDispatch", idispatch.DispatchIID},
+    {"IPersistMoniker", ipersistmoniker.PersistMonikerIID},
+  }
+)
+
+// DcomScan reports which DCOM classes can be activated on the remote host
+type DcomScan struct {
+  Dcom
+
+  // Classes are class IDs to scan in addition to KnownClasses, optionally named (i.e. Outlook.Application=0006F03A-0000-0000-C000-000000000046)
+  Classes []string
+
+  // NoKnown only scans Classes
+  NoKnown bool
+
+  // NoRegistry disables resolving VisualStudioDteProgIds through the remote registry, which requires SMB
+  NoRegistry bool
+
+  // Format is the output format, either ScanFormatJson (JSON lines) or ScanFormatTable
+  Format string
+
+  Out io.Writer
+}
+
+type scanResult struct {
+  Name       string   `json:"name,omitempty"`
+  Clsid      string   `json:"clsid"`
+  Registered bool     `json:"registered"`
+  Launchable bool     `json:"launchable"`
+  Interfaces []string `json:"interfaces"`
+  TypeInfo   bool     `json:"typeinfo"`
+  Error      string   `json:"error,omitempty"`
+}
+
+func (m *DcomScan) Call(ctx context.Context) (err error) {
+  log := zerolog.Ctx(ctx)
+
+  classes, err := m.classes(ctx)
+  if err != nil {
+    return
+  }
+  var results []scanResult
+
+  for _, class := range classes {
+    res := m.scan(ctx, class)
+    log.Info().
+      Str("class", res.Name).
+      Str("clsid", res.Clsid).
+      Bool("registered", res.Registered).
+      Bool("launchable", res.Launchable).
+      Msg("Scanned class")
+
+    if m.Format == ScanFormatTable {
+      results = append(results, res)
+      continue
+    }
+    if err = writeScanResult(m.Out, res); err != nil {
+      return
+    }
+  }
+  if m.Format == ScanFormatTable {
+    return writeScanTable(m.Out, results)
+  }
+  return
+}
+
+// classes returns the classes to scan. The known classes include the VisualStudio.DTE classes resolved
+// from the remote registry, unless NoRegistry is set
+func (m *DcomScan) classes(ctx context.Context) (classes []ScanClass, err error) {
+  if !m.NoKnown {
+    classes = append(classes, KnownClasses...)
+
+    if !m.NoRegistry {
+      resolved, err := m.resolveProgIds(ctx)
+      if err != nil {
+        zerolog.Ctx(ctx).Warn().Err(err).Msg("Failed to resolve VisualStudio.DTE classes from the remote registry")
+      }
+      for _, r := range resolved {
+        if !slices.ContainsFunc(classes, func(c ScanClass) bool { return strings.EqualFold(c.Clsid, r.Clsid) }) {
+          classes = append(classes, r)
+        }
+      }
+    }
+  }
+  for _, c := range m.Classes {
+    class := ScanClass{Clsid: c}
+
+    if name, clsid, ok := strings.Cut(c, "="); ok {
+      class = ScanClass{Name: name, Clsid: clsid}
+    }
+    if _, err = uuid.Parse(class.Clsid); err != nil {
+      return nil, fmt.Errorf("parse CLSID %q: %w", class.Clsid, err)
+    }
+    classes = append(classes, class)
+  }
+  return
+}
+
+// scan activates a class with RemoteCreateInstance, then checks for IDispatch type information
+// on the object exporter. The acquired interfaces are released before returning
+func (m *DcomScan) scan(ctx context.Context, class ScanClass) (res scanResult) {
+  log := zerolog.Ctx(ctx).With().Str("clsid", class.Clsid).Logger()
+
+  res = scanResult{Name: class.Name, Clsid: strings.ToUpper(class.Clsid), Registered: true, Interfaces: []string{}}
+
+  iids := make([]*dcom.IID, len(scanInterfaces))
+  for i := range scanInterfaces {
+    iids[i] = scanInterfaces[i].iid
+  }
+  reply, out, err := m.createInstance(ctx, m.Client.Dce(), uuid.MustParse(class.Clsid), iids...)
+  if err != nil {
+    if isClassNotRegistered(err) {
+      res.Registered = false
+    }
+    res.Error = err.Error()
+    return
+  }
+  res.Launchable = true
+
+  // The object is bound and released through a separate connection to its exporter
+  cl := *m.Client
+
+  obj := &Dispatch{Dcom: Dcom{Client: &cl, comVersion: m.comVersion, oxid: reply.OXID, remUnknown: reply.IPIDRemoteUnknown}}
+  var disp *dcom.IPID
+
+  for i, hr := range out.HResults {
+    if hr != 0 || i >= len(out.InterfaceData) || i >= len(scanInterfaces) {
+      continue
+    }
+    res.Interfaces = append(res.Interfaces, scanInterfaces[i].name)
+    obj.track(out.InterfaceData[i])
+
+    if scanInterfaces[i].iid == idispatch.DispatchIID {
+      disp = out.InterfaceData[i].GetStandardObjectReference().Std.IPID
+    }
+  }
+  bindings, smb, err := m.selectBindings(ctx, reply.OXIDBindings.GetStringBindings())
+  if err != nil {
+    log.Warn().Err(err).Msg("Failed to select object exporter binding, acquired interfaces won't be released")
+    res.Error = err.Error()
+    return
+  }
+  cl.Smb = cl.Smb || smb
+  cl.DcerpcOptions = append(append([]dcerpc.Option{}, m.Client.DcerpcOptions...), bindings...)
+
+  if err = cl.Connect(ctx); err != nil {
+    log.Warn().Err(err).Msg("Failed to connect to object exporter, acquired interfaces won't be released")
+    res.Error = fmt.Sprintf("connect to object exporter: %v", err)
+    return
+  }
+  defer func() {
+    if err := obj.release(ctx); err != nil {
+      log.Warn().Err(err).Msg("Failed to release remote objects")
+    }
+    if err := cl.Close(ctx); err != nil {
+      log.Debug().Err(err).Msg("Failed to close object exporter connection")
+    }
+  }()
+
+  if disp != nil {
+    if res.TypeInfo, err = obj.hasTypeInfo(ctx, disp); err != nil {
+      log.Debug().Err(err).Msg("Type information is not available")
+    }
+  }
+  return
+}
+
+// hasTypeInfo determines whether the IDispatch interface id provides type information
+func (m *Dispatch) hasTypeInfo(ctx context.Context, id *dcom.IPID) (ok bool, err error) {
+  if m.dispatch, err = idispatch.NewDispatchClient(ctx, m.Client.Dce(), dcom.WithIPID(id)); err != nil {
+    return false, fmt.Errorf("init IDispatch client: %w", err)
+  }
+  tc, err := m.dispatch.GetTypeInfoCount(ctx, &idispatch.GetTypeInfoCountRequest{
+    This: &dcom.ORPCThis{Version: m.comVersion},
+  })
+  if err != nil {
+    return false, fmt.Errorf("get type info count: %w", err)
+  }
+  if tc.TypeInfoCount == 0 {
+    return false, nil
+  }
+  ti, err := m.dispatch.GetTypeInfo(ctx, &idispatch.GetTypeInfoRequest{
+    This:     &dcom.ORPCThis{Version: m.comVersion},
+    LocaleID: LcEnglishUs,
+  })
+  if err != nil {
+    return false, fmt.Errorf("get type info: %w", err)
+  }
+  if ti.TypeInfo == nil {
+    return false, nil
+  }
+  m.track(ti.TypeInfo.InterfacePointer())
+  return true, nil
+}
+
+func writeScanResult(w io.Writer, res scanResult) error {
+  if w == nil {
+    return nil
+  }
+  out, err := json.Marshal(res)
+  if err != nil {
+    return fmt.Errorf("marshal output: %w", err)
+  }
+  if _, err = w.Write(append(out, 0x0a)); err != nil {
+    return fmt.Errorf("write output: %w", err)
+  }
+  return nil
+}
+
+func writeScanTable(w io.Writer, results []scanResult) error {
+  if w == nil {
+    return nil
+  }
+  tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+  _, _ = fmt.Fprintln(tw, "Name\tCLSID\tRegistered\tLaunchable\tInterfaces\tTypeInfo\tError")
+
+  for _, res := range results {
+    _, _ = fmt.Fprintf(tw, "%s\t%s\t%t\t%t\t%s\t%t\t%s\n",
+      res.Name, res.Clsid, res.Registered, res.Launchable, strings.Join(res.Interfaces, ","), res.TypeInfo, res.Error)
+  }
+  if err := tw.Flush(); err != nil {
+    return fmt.Errorf("write output: %w", err)
+  }
+  return nil
+}
@@ -0,0 +1,86 @@
+package dcomexec
+
+import (
+  "context"
+  "fmt"
+  "slices"
+  "strconv"
+
+  "github.com/FalconOpsLLC/goexec/pkg/goexec"
+  "github.com/oiweiwei/go-msrpc/midl/uuid"
+  "github.com/oiweiwei/go-msrpc/msrpc/dcom"
+  "github.com/oiweiwei/go-msrpc/msrpc/dcom/oaut"
+  "github.com/oiweiwei/go-msrpc/msrpc/erref/hresult"
+  "github.com/rs/zerolog"
+)
+
+const (
+  MethodShellApplication = "Shell.Application" // Shell.Application::ShellExecute
+  ShellApplicationUuid   = "13709620-C279-11CE-A49E-444553540000"
+)
+
+// ShellWindowStates are the documented vShow values of Shell.ShellExecute: 0 (hidden), 1 (normal), 2 (minimized),
+// 3 (maximized), 4 (normal, not activated), 5 (current size and position), 7 (minimized, not activated) and 10 (default).
+// See https://learn.microsoft.com/en-us/windows/win32/shell/shell-shellexecute
+var ShellWindowStates = []string{"0", "1", "2", "3", "4", "5", "7", "10"}
+
+// ShellExecuteOptions are the options of methods that spawn processes with Shell.ShellExecute
+type ShellExecuteOptions struct {
+  // WorkingDirectory is the working directory of the process
+  WorkingDirectory string
+
+  // WindowState is the application window state, one of ShellWindowStates
+  WindowState string
+
+  // Verb is the operation to perform (i.e. open, runas, print). The default operation is used if empty
+  Verb string
+}
+
+type DcomShellApplication struct {
+  Dispatch
+  ShellExecuteOptions
+}
+
+// shellExecute calls member, a Shell.ShellExecute method on the object id, to spawn the process of execIO
+func (m *Dispatch) shellExecute(ctx context.Context, id *dcom.IPID, member string, execIO *goexec.ExecutionIO, opts ShellExecuteOptions) (err error) {
+  log := zerolog.Ctx(ctx).With().Str("call", member).Logger()
+
+  if opts.WindowState == "" {
+    opts.WindowState = "0"
+  }
+  if !slices.Contains(ShellWindowStates, opts.WindowState) {
+    return fmt.Errorf("invalid window state %q", opts.WindowState)
+  }
+  show, _ := strconv.ParseInt(opts.WindowState, 10, 32)
+  cmdline := execIO.CommandLine()
+
+  // ShellExecute(sFile, vArguments, vDirectory, vOperation, vShow) - arguments must be passed in reverse order
+  ir, err := m.callComMethod(ctx, id, member,
+    newVariant(vtI4, &oaut.Variant_VarUnion{Value: &oaut.Variant_VarUnion_Long{Long: int32(show)}}),
+    stringToVariant(opts.Verb),
+    stringToVariant(opts.WorkingDirectory),
+    stringToVariant(cmdline[1]),
+    stringToVariant(cmdline[0]))
+
+  if err != nil {
+    return fmt.Errorf("call %q: %w", member, err)
+  }
+  if ir.Return != 0 {
+    return fmt.Errorf("call %q: %w", member, hresult.FromCode(uint32(ir.Return)))
+  }
+  log.Info().Str("verb", opts.Verb).Msg("Method call successful")
+  return
+}
+
+// Init will initialize the Shell.Application instance
+func (m *DcomShellApplication) Init(ctx context.Context) (err error) {
+  if err = m.Dcom.Init(ctx); err == nil {
+    return m.getDispatch(ctx, uuid.MustParse(ShellApplicationUuid))
+  }
+  return
+}
+
+// Execute will perform command execution via Shell.Application.ShellExecute
+func (m *DcomShellApplication) Execute(ctx context.Context, execIO *goexec.ExecutionIO) (err error) {
+  return m.shellExecute(ctx, nil, "ShellExecute", execIO, m.ShellExecuteOptions)
+}
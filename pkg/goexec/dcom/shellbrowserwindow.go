@@ -2,11 +2,9 @@ package dcomexec
 
 import (
   "context"
-  "fmt"
 
   "github.com/FalconOpsLLC/goexec/pkg/goexec"
   "github.com/oiweiwei/go-msrpc/midl/uuid"
-  "github.com/rs/zerolog"
 
   _ "github.com/oiweiwei/go-msrpc/msrpc/erref/hresult"
   _ "github.com/oiweiwei/go-msrpc/msrpc/erref/ntstatus"
@@ -20,8 +18,7 @@ const (
 
 type DcomShellBrowserWindow struct {
   Dispatch
-  WorkingDirectory string
-  WindowState      string
+  ShellExecuteOptions
 }
 
 // Init will initialize the ShellBrowserWindow instance
@@ -34,19 +31,5 @@ func (m *DcomShellBrowserWindow) Init(ctx context.Context) (err error) {
 
 // Execute will perform command execution via the ShellBrowserWindow object. See https://enigma0x3.net/2017/01/23/lateral-movement-via-dcom-round-2/
 func (m *DcomShellBrowserWindow) Execute(ctx context.Context, execIO *goexec.ExecutionIO) (err error) {
-  method := "Document.Application.ShellExecute"
-  cmdline := execIO.CommandLine()
-
-  // Arguments must be passed in reverse order
-  if _, err := m.callComMethod(ctx, nil, method,
-    stringToVariant(m.WindowState),
-    stringToVariant(""), // FUTURE?
-    stringToVariant(m.WorkingDirectory),
-    stringToVariant(cmdline[1]),
-    stringToVariant(cmdline[0])); err != nil {
-
-    return fmt.Errorf("call %q: %w", method, err)
-  }
-  zerolog.Ctx(ctx).Info().Msg("Method call successful")
-  return
+  return m.shellExecute(ctx, nil, "Document.Application.ShellExecute", execIO, m.ShellExecuteOptions)
 }
@@ -22,9 +22,7 @@ const (
 
 type DcomShellWindows struct {
   Dispatch
-
-  WorkingDirectory string
-  WindowState      string
+  ShellExecuteOptions
 }
 
 // Init will initialize the ShellWindows instance
@@ -48,18 +46,6 @@ func (m *DcomShellWindows) Execute(ctx context.Context, execIO *goexec.Execution
   if !ok {
     return errors.New("failed to get dispatch from ShellWindows::Item()")
   }
-  method = "Document.Application.ShellExecute"
-  cmdline := execIO.CommandLine()
-
-  // Arguments must be passed in reverse order
-  if _, err := m.callComMethod(ctx, item.InterfacePointer().GetStandardObjectReference().Std.IPID, method,
-    stringToVariant(m.WindowState),
-    stringToVariant(""), // FUTURE?
-    stringToVariant(m.WorkingDirectory),
-    stringToVariant(cmdline[1]),
-    stringToVariant(cmdline[0])); err != nil {
-    return fmt.Errorf("call %q: %w", method, err)
-  }
-  log.Info().Msg("Method call successful")
-  return
+  return m.shellExecute(ctx, item.InterfacePointer().GetStandardObjectReference().Std.IPID,
+    "Document.Application.ShellExecute", execIO, m.ShellExecuteOptions)
 }
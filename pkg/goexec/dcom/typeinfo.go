@@ -0,0 +1,434 @@
+package dcomexec
+
+import (
+  "context"
+  "encoding/json"
+  "fmt"
+  "io"
+  "strings"
+
+  "github.com/oiweiwei/go-msrpc/midl/uuid"
+  "github.com/oiweiwei/go-msrpc/msrpc/dcom"
+  "github.com/oiweiwei/go-msrpc/msrpc/dcom/oaut"
+  "github.com/oiweiwei/go-msrpc/msrpc/dcom/oaut/idispatch/v0"
+  "github.com/oiweiwei/go-msrpc/msrpc/dcom/oaut/itypeinfo/v0"
+  "github.com/rs/zerolog"
+)
+
+const (
+  MethodTypeInfo = "TypeInfo"
+
+  TypeInfoFormatText = "text"
+  TypeInfoFormatJson = "json"
+
+  // memberIdNil (MEMBERID_NIL) refers to the type itself in ITypeInfo::GetDocumentation
+  memberIdNil int32 = -1
+
+  // typeInfoNameArg (TYPEINFO_NameArg) requests the name from ITypeInfo::GetDocumentation
+  typeInfoNameArg uint32 = 0x1
+
+  // varFlagReadOnly (VARFLAG_FREADONLY) is set on data members that can't be assigned
+  varFlagReadOnly uint16 = 0x1
+)
+
+// baseTypeNames are the names of the TYPEDESC types that don't refer to other types
+var baseTypeNames = map[uint16]string{
+  2:  "short",
+  3:  "long",
+  4:  "float",
+  5:  "double",
+  6:  "CURRENCY",
+  7:  "DATE",
+  8:  "BSTR",
+  9:  "IDispatch*",
+  10: "SCODE",
+  11: "VARIANT_BOOL",
+  12: "VARIANT",
+  13: "IUnknown*",
+  14: "DECIMAL",
+  16: "char",
+  17: "unsigned char",
+  18: "unsigned short",
+  19: "unsigned long",
+  20: "int64",
+  21: "uint64",
+  22: "int",
+  23: "unsigned int",
+  24: "void",
+  25: "HRESULT",
+  30: "LPSTR",
+  31: "LPWSTR",
+}
+
+// DcomTypeInfo describes the interface of a DCOM object using the type information provided by IDispatch
+type DcomTypeInfo struct {
+  Dispatch
+
+  // Clsid is the class ID of the object to instantiate
+  Clsid string
+
+  // Path is a dot-separated property path (i.e. Document.ActiveView). If set, the object returned
+  // by the path is described instead of the instantiated object
+  Path string
+
+  // Format is the output format, either TypeInfoFormatText (default) or TypeInfoFormatJson
+  Format string
+
+  Out io.Writer
+
+  typeInfo  itypeinfo.TypeInfoClient
+  hrefNames map[uint32]string
+}
+
+type interfaceInfo struct {
+  Name       string       `json:"name"`
+  GUID       string       `json:"guid"`
+  Kind       string       `json:"kind"`
+  Functions  []funcInfo   `json:"functions"`
+  Properties []memberInfo `json:"properties,omitempty"`
+}
+
+type funcInfo struct {
+  memberInfo
+  Invoke string      `json:"invoke"`
+  Params []paramDesc `json:"params"`
+}
+
+type memberInfo struct {
+  Name     string `json:"name"`
+  MemberID int32  `json:"member_id"`
+  Type     string `json:"type"`
+  ReadOnly bool   `json:"read_only,omitempty"`
+}
+
+type paramDesc struct {
+  Name  string   `json:"name"`
+  Type  string   `json:"type"`
+  Flags []string `json:"flags,omitempty"`
+}
+
+// Init will initialize the IDispatch instance of Clsid
+func (m *DcomTypeInfo) Init(ctx context.Context) (err error) {
+  cls, err := uuid.Parse(m.Clsid)
+  if err != nil {
+    return fmt.Errorf("parse CLSID: %w", err)
+  }
+  if err = m.Dcom.Init(ctx); err == nil {
+    return m.getDispatch(ctx, cls)
+  }
+  return
+}
+
+func (m *DcomTypeInfo) Call(ctx context.Context) (err error) {
+  log := zerolog.Ctx(ctx).With().
+    Str("clsid", m.Clsid).
+    Str("path", m.Path).
+    Logger()
+
+  var id *dcom.IPID
+
+  if m.Path != "" {
+    if id, _, err = m.invokeObject(ctx, nil, m.Path, DispatchPropertyGet); err != nil {
+      return fmt.Errorf("get %q: %w", m.Path, err)
+    }
+  }
+  if err = m.bindTypeInfo(ctx, id); err != nil {
+    return
+  }
+  info, err := m.describe(ctx)
+  if err != nil {
+    return
+  }
+  log.Info().
+    Str("interface", info.Name).
+    Int("functions", len(info.Functions)).
+    Int("properties", len(info.Properties)).
+    Msg("Type information retrieved")
+
+  if m.Out == nil {
+    return
+  }
+  if m.Format == TypeInfoFormatJson {
+    out, err := json.Marshal(info)
+    if err != nil {
+      return fmt.Errorf("marshal output: %w", err)
+    }
+    _, err = m.Out.Write(append(out, 0x0a))
+    return err
+  }
+  _, err = io.WriteString(m.Out, info.String())
+  return
+}
+
+// bindTypeInfo calls IDispatch::GetTypeInfo on the object id (or the instantiated object if nil),
+// then binds an ITypeInfo client to the returned type information
+func (m *DcomTypeInfo) bindTypeInfo(ctx context.Context, id *dcom.IPID) (err error) {
+  dispatch := m.dispatch
+  if id != nil {
+    dispatch = m.dispatch.IPID(ctx, id)
+  }
+  tc, err := dispatch.GetTypeInfoCount(ctx, &idispatch.GetTypeInfoCountRequest{
+    This: &dcom.ORPCThis{Version: m.comVersion},
+  })
+  if err != nil {
+    return fmt.Errorf("get type info count: %w", err)
+  }
+  if tc.TypeInfoCount == 0 {
+    return fmt.Errorf("object doesn't provide type information")
+  }
+  tr, err := dispatch.GetTypeInfo(ctx, &idispatch.GetTypeInfoRequest{
+    This:     &dcom.ORPCThis{Version: m.comVersion},
+    LocaleID: LcEnglishUs,
+  })
+  if err != nil {
+    return fmt.Errorf("get type info: %w", err)
+  }
+  if tr.TypeInfo == nil {
+    return fmt.Errorf("get type info: no type information returned")
+  }
+  m.track(tr.TypeInfo.InterfacePointer())
+
+  m.typeInfo, err = itypeinfo.NewTypeInfoClient(ctx, m.Client.Dce(),
+    dcom.WithIPID(tr.TypeInfo.InterfacePointer().GetStandardObjectReference().Std.IPID))
+  if err != nil {
+    return fmt.Errorf("init ITypeInfo client: %w", err)
+  }
+  return
+}
+
+// describe walks the functions and data members of the bound type information
+func (m *DcomTypeInfo) describe(ctx context.Context) (info *interfaceInfo, err error) {
+  this := &dcom.ORPCThis{Version: m.comVersion}
+
+  ta, err := m.typeInfo.GetTypeAttribute(ctx, &itypeinfo.GetTypeAttributeRequest{This: this})
+  if err != nil {
+    return nil, fmt.Errorf("get type attributes: %w", err)
+  }
+  attr := ta.TypeAttribute
+  info = &interfaceInfo{
+    Name:      m.typeName(ctx, m.typeInfo),
+    Kind:      strings.ToLower(strings.TrimPrefix(attr.TypeKind.String(), "TypeKind")),
+    Functions: []funcInfo{},
+  }
+  if attr.GUID != nil {
+    info.GUID = strings.ToUpper(attr.GUID.UUID().String())
+  }
+
+  for i := uint32(0); i < uint32(attr.FuncsCount); i++ {
+    fr, err := m.typeInfo.GetFuncDesc(ctx, &itypeinfo.GetFuncDescRequest{This: this, Index: i})
+    if err != nil {
+      return nil, fmt.Errorf("get function %d: %w", i, err)
+    }
+    fd := fr.FuncDesc
+    if oaut.FuncFlags(fd.FuncFlags)&oaut.FuncFlagsRestricted != 0 {
+      continue // i.e. IUnknown and IDispatch methods
+    }
+    names, err := m.names(ctx, fd.MemberID, uint32(fd.ParamsCount)+1)
+    if err != nil {
+      return nil, err
+    }
+    fi := funcInfo{
+      memberInfo: memberInfo{Name: names[0], MemberID: fd.MemberID, Type: "void"},
+      Invoke:     invokeKindName(fd.InvokeKind),
+      Params:     []paramDesc{},
+    }
+    if fd.ElemDescFunc != nil {
+      fi.Type = m.elemTypeName(ctx, fd.ElemDescFunc.TypeDesc)
+    }
+    for j, pd := range fd.LprgelemdescParam {
+      p := paramDesc{Name: "value", Type: m.elemTypeName(ctx, pd.TypeDesc)}
+
+      if j+1 < len(names) {
+        p.Name = names[j+1]
+      }
+      if pd.ParamDesc != nil {
+        p.Flags = paramFlagNames(oaut.ParamFlags(pd.ParamDesc.ParamFlags))
+      }
+      if j >= int(fd.ParamsCount-fd.ParamsOptCount) && fd.ParamsOptCount > 0 && !hasFlag(p.Flags, "optional") {
+        p.Flags = append(p.Flags, "optional")
+      }
+      fi.Params = append(fi.Params, p)
+    }
+    info.Functions = append(info.Functions, fi)
+  }
+
+  for i := uint32(0); i < uint32(attr.VarsCount); i++ {
+    vr, err := m.typeInfo.GetVarDesc(ctx, &itypeinfo.GetVarDescRequest{This: this, Index: i})
+    if err != nil {
+      return nil, fmt.Errorf("get data member %d: %w", i, err)
+    }
+    vd := vr.VarDesc
+    names, err := m.names(ctx, vd.MemberID, 1)
+    if err != nil {
+      return nil, err
+    }
+    mi := memberInfo{Name: names[0], MemberID: vd.MemberID, ReadOnly: vd.VarFlags&varFlagReadOnly != 0}
+    if vd.ElemDescVar != nil {
+      mi.Type = m.elemTypeName(ctx, vd.ElemDescVar.TypeDesc)
+    }
+    info.Properties = append(info.Properties, mi)
+  }
+  return
+}
+
+// names returns the name of a member followed by the names of its parameters. The first name is always set
+func (m *DcomTypeInfo) names(ctx context.Context, memberId int32, max uint32) ([]string, error) {
+  nr, err := m.typeInfo.GetNames(ctx, &itypeinfo.GetNamesRequest{
+    This:          &dcom.ORPCThis{Version: m.comVersion},
+    MemberID:      memberId,
+    MaxNamesCount: max,
+  })
+  if err != nil {
+    return nil, fmt.Errorf("get names of member %d: %w", memberId, err)
+  }
+  names := make([]string, 0, len(nr.Names))
+
+  for _, n := range nr.Names {
+    if n != nil {
+      names = append(names, n.Data)
+    } else {
+      names = append(names, "")
+    }
+  }
+  if len(names) == 0 {
+    names = append(names, fmt.Sprintf("member_%d", memberId))
+  }
+  return names, nil
+}
+
+// typeName returns the name of the type described by ti
+func (m *DcomTypeInfo) typeName(ctx context.Context, ti itypeinfo.TypeInfoClient) string {
+  dr, err := ti.GetDocumentation(ctx, &itypeinfo.GetDocumentationRequest{
+    This:         &dcom.ORPCThis{Version: m.comVersion},
+    MemberID:     memberIdNil,
+    PointerFlags: typeInfoNameArg,
+  })
+  if err != nil || dr.Name == nil {
+    return "?"
+  }
+  return dr.Name.Data
+}
+
+// elemTypeName returns the name of a TYPEDESC. User-defined types are resolved with ITypeInfo::GetRefTypeInfo
+func (m *DcomTypeInfo) elemTypeName(ctx context.Context, td *oaut.TypeDesc) string {
+  if td == nil {
+    return "?"
+  }
+  if name, ok := baseTypeNames[td.VT]; ok {
+    return name
+  }
+  switch val := td.Union.GetValue().(type) {
+  case *oaut.TypeDesc:
+    switch td.VT {
+    case 26: // VT_PTR
+      return m.elemTypeName(ctx, val) + "*"
+    case 27: // VT_SAFEARRAY
+      return "SAFEARRAY(" + m.elemTypeName(ctx, val) + ")"
+    }
+  case *oaut.ArrayDesc:
+    if val != nil {
+      dims := ""
+      for _, b := range val.Bounds {
+        dims += fmt.Sprintf("[%d]", b.ElementsCount)
+      }
+      return m.elemTypeName(ctx, val.TypeDescElem) + dims
+    }
+  case uint32:
+    return m.hrefName(ctx, val)
+  }
+  return fmt.Sprintf("VT(%d)", td.VT)
+}
+
+// hrefName resolves the name of a user-defined type
+func (m *DcomTypeInfo) hrefName(ctx context.Context, href uint32) string {
+  if name, ok := m.hrefNames[href]; ok {
+    return name
+  }
+  name := fmt.Sprintf("HREFTYPE(%#x)", href)
+
+  rr, err := m.typeInfo.GetReferenceTypeInfo(ctx, &itypeinfo.GetReferenceTypeInfoRequest{
+    This:       &dcom.ORPCThis{Version: m.comVersion},
+    TypeHandle: href,
+  })
+  if err == nil && rr.TypeInfo != nil {
+    m.track(rr.TypeInfo.InterfacePointer())
+    name = m.typeName(ctx, m.typeInfo.IPID(ctx, rr.TypeInfo.InterfacePointer().GetStandardObjectReference().Std.IPID))
+  } else if err != nil {
+    zerolog.Ctx(ctx).Debug().Err(err).Uint32("href", href).Msg("Failed to resolve user-defined type")
+  }
+  if m.hrefNames == nil {
+    m.hrefNames = make(map[uint32]string)
+  }
+  m.hrefNames[href] = name
+  return name
+}
+
+// String returns an IDL-like description of the interface
+func (info *interfaceInfo) String() string {
+  var b strings.Builder
+
+  _, _ = fmt.Fprintf(&b, "%s %s {%s}\n", info.Kind, info.Name, info.GUID)
+
+  for _, p := range info.Properties {
+    attrs := fmt.Sprintf("id(0x%08x)", uint32(p.MemberID))
+    if p.ReadOnly {
+      attrs += ", readonly"
+    }
+    _, _ = fmt.Fprintf(&b, "  [%s] %s %s;\n", attrs, p.Type, p.Name)
+  }
+  for _, f := range info.Functions {
+    attrs := fmt.Sprintf("id(0x%08x)", uint32(f.MemberID))
+    if f.Invoke != "method" {
+      attrs += ", " + f.Invoke
+    }
+    params := make([]string, len(f.Params))
+    for i, p := range f.Params {
+      params[i] = p.Type + " " + p.Name
+      if len(p.Flags) > 0 {
+        params[i] = "[" + strings.Join(p.Flags, ", ") + "] " + params[i]
+      }
+    }
+    _, _ = fmt.Fprintf(&b, "  [%s] %s %s(%s);\n", attrs, f.Type, f.Name, strings.Join(params, ", "))
+  }
+  return b.String()
+}
+
+func invokeKindName(kind oaut.InvokeKind) string {
+  switch kind {
+  case oaut.InvokeKindPropertyGet:
+    return "propget"
+  case oaut.InvokeKindPropertyPut:
+    return "propput"
+  case oaut.InvokeKindPropertyPutReference:
+    return "propputref"
+  }
+  return "method"
+}
+
+func paramFlagNames(flags oaut.ParamFlags) (names []string) {
+  for _, f := range []struct {
+    flag oaut.ParamFlags
+    name string
+  }{
+    {oaut.ParamFlagsIn, "in"},
+    {oaut.ParamFlagsOut, "out"},
+    {oaut.ParamFlagsLocaleID, "lcid"},
+    {oaut.ParamFlagsReturnValue, "retval"},
+    {oaut.ParamFlagsOptional, "optional"},
+    {oaut.ParamFlagsHasDefault, "defaultvalue"},
+  } {
+    if flags&f.flag != 0 {
+      names = append(names, f.name)
+    }
+  }
+  return
+}
+
+func hasFlag(flags []string, flag string) bool {
+  for _, f := range flags {
+    if f == flag {
+      return true
+    }
+  }
+  return false
+}
@@ -29,22 +29,11 @@ func getComVersion(ctx context.Context, cc dcerpc.Conn) (ver *dcom.COMVersion, e
   return cv, nil
 }
 
-// normalizeStringBindings removes the address/hostname from string bindings to prevent name resolution issues.
-func normalizeStringBindings(bindings []*dcom.StringBinding) (opts []dcerpc.Option) {
-  for _, b := range bindings {
-    if s, err := dcerpc.ParseStringBinding(b.String()); err == nil {
-      s.NetworkAddress = ""
-      opts = append(opts, dcerpc.WithEndpoint(s.String()))
-    }
-  }
-  return
-}
-
 // stringToVariant converts a string to a *oaut.Variant.
 func stringToVariant(s string) *oaut.Variant {
   return &oaut.Variant{
     Size: 5,
-    VT:   8,
+    VT:   vtBstr,
     VarUnion: &oaut.Variant_VarUnion{
       Value: &oaut.Variant_VarUnion_BSTR{
         BSTR: &oaut.String{
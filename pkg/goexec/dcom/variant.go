@@ -0,0 +1,150 @@
+package dcomexec
+
+import (
+  "fmt"
+  "strconv"
+  "strings"
+
+  "github.com/oiweiwei/go-msrpc/msrpc/dcom"
+  "github.com/oiweiwei/go-msrpc/msrpc/dcom/oaut"
+)
+
+// VARIANT types. See https://learn.microsoft.com/en-us/openspecs/windows_protocols/ms-oaut/3fe7db9f-5803-4dc4-9d14-5425d3f5461f
+const (
+  vtEmpty    uint16 = 0
+  vtNull     uint16 = 1
+  vtI2       uint16 = 2
+  vtI4       uint16 = 3
+  vtR4       uint16 = 4
+  vtR8       uint16 = 5
+  vtBstr     uint16 = 8
+  vtDispatch uint16 = 9
+  vtBool     uint16 = 11
+  vtUi1      uint16 = 17
+  vtUi4      uint16 = 19
+  vtI8       uint16 = 20
+  vtUi8      uint16 = 21
+)
+
+// VariantGetPrefix marks an argument that is the result of a property get on the
+// instantiated object (i.e. "get:Document.ActiveView"), used to pass objects as arguments
+const VariantGetPrefix = "get:"
+
+// parseVariant converts a typed argument to a *oaut.Variant. Arguments have the form "type:value", where type is one of
+// str, int (i4), short (i2), long (i8), uint (ui4), ulong (ui8), byte (ui1), float (r4), double (r8) or bool.
+// The arguments "null" and "empty" have no value. Arguments without a known type are passed as strings
+func parseVariant(arg string) (v *oaut.Variant, err error) {
+  switch strings.ToLower(arg) {
+  case "null":
+    return newVariant(vtNull, &oaut.Variant_VarUnion{}), nil
+  case "empty":
+    return newVariant(vtEmpty, &oaut.Variant_VarUnion{}), nil
+  }
+  typ, val, ok := strings.Cut(arg, ":")
+  if !ok {
+    return stringToVariant(arg), nil
+  }
+  parseInt := func(bits int) (i int64) {
+    if i, err = strconv.ParseInt(val, 0, bits); err != nil {
+      err = fmt.Errorf("parse %s argument %q: %w", typ, val, err)
+    }
+    return
+  }
+  parseUint := func(bits int) (u uint64) {
+    if u, err = strconv.ParseUint(val, 0, bits); err != nil {
+      err = fmt.Errorf("parse %s argument %q: %w", typ, val, err)
+    }
+    return
+  }
+  parseFloat := func(bits int) (f float64) {
+    if f, err = strconv.ParseFloat(val, bits); err != nil {
+      err = fmt.Errorf("parse %s argument %q: %w", typ, val, err)
+    }
+    return
+  }
+
+  switch strings.ToLower(typ) {
+  case "str", "string", "bstr":
+    return stringToVariant(val), nil
+  case "int", "i4":
+    v = newVariant(vtI4, &oaut.Variant_VarUnion{Value: &oaut.Variant_VarUnion_Long{Long: int32(parseInt(32))}})
+  case "short", "i2":
+    v = newVariant(vtI2, &oaut.Variant_VarUnion{Value: &oaut.Variant_VarUnion_Short{Short: int16(parseInt(16))}})
+  case "long", "i8":
+    v = newVariant(vtI8, &oaut.Variant_VarUnion{Value: &oaut.Variant_VarUnion_LongLongValue{LongLongValue: parseInt(64)}})
+  case "uint", "ui4":
+    v = newVariant(vtUi4, &oaut.Variant_VarUnion{Value: &oaut.Variant_VarUnion_Ulong{Ulong: uint32(parseUint(32))}})
+  case "ulong", "ui8":
+    v = newVariant(vtUi8, &oaut.Variant_VarUnion{Value: &oaut.Variant_VarUnion_UlongLong{UlongLong: parseUint(64)}})
+  case "byte", "ui1":
+    v = newVariant(vtUi1, &oaut.Variant_VarUnion{Value: &oaut.Variant_VarUnion_Byte{Byte: uint8(parseUint(8))}})
+  case "float", "r4":
+    v = newVariant(vtR4, &oaut.Variant_VarUnion{Value: &oaut.Variant_VarUnion_Float{Float: float32(parseFloat(32))}})
+  case "double", "r8":
+    v = newVariant(vtR8, &oaut.Variant_VarUnion{Value: &oaut.Variant_VarUnion_Double{Double: parseFloat(64)}})
+  case "bool":
+    b, pe := strconv.ParseBool(val)
+    if pe != nil {
+      return nil, fmt.Errorf("parse bool argument %q: %w", val, pe)
+    }
+    v = newVariant(vtBool, &oaut.Variant_VarUnion{Value: &oaut.Variant_VarUnion_Bool{Bool: variantBool(b)}})
+  default:
+    // Not a type prefix (i.e. C:\Windows)
+    return stringToVariant(arg), nil
+  }
+  if err != nil {
+    return nil, err
+  }
+  return
+}
+
+func newVariant(vt uint16, u *oaut.Variant_VarUnion) *oaut.Variant {
+  return &oaut.Variant{Size: 5, VT: vt, VarUnion: u}
+}
+
+// variantBool returns the VARIANT_BOOL representation of b
+func variantBool(b bool) int16 {
+  if b {
+    return -1
+  }
+  return 0
+}
+
+// variantValue returns a printable value of a VARIANT result
+func variantValue(v *oaut.Variant) any {
+  if v == nil || v.VarUnion == nil {
+    return nil
+  }
+  switch val := v.VarUnion.GetValue().(type) {
+  case *oaut.String:
+    if val == nil {
+      return ""
+    }
+    return val.Data
+  case int16:
+    if v.VT == vtBool {
+      return val != 0
+    }
+    return val
+  case *oaut.Dispatch:
+    return variantObject("IDispatch", val.InterfacePointer().GetStandardObjectReference())
+  case *dcom.Unknown:
+    return variantObject("IUnknown", val.InterfacePointer().GetStandardObjectReference())
+  default:
+    return val
+  }
+}
+
+// variantObject describes an interface pointer returned in a VARIANT
+func variantObject(iface string, ref *dcom.ObjectReferenceStandard) map[string]any {
+  obj := map[string]any{"interface": iface}
+
+  if ref.Std != nil {
+    obj["oxid"] = fmt.Sprintf("%016x", ref.Std.OXID)
+    obj["oid"] = fmt.Sprintf("%016x", ref.Std.OID)
+    if ref.Std.IPID != nil {
+      obj["ipid"] = ref.Std.IPID.UUID().String()
+    }
+  }
+  return obj
+}
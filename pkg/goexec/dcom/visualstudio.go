@@ -1,48 +1,253 @@
 package dcomexec
 
-import "github.com/rs/zerolog"
-
 /*
 See https://learn.microsoft.com/en-us/dotnet/api/envdte._dte.executecommand
 */
 
 import (
   "context"
+  "errors"
+  "fmt"
+  "strings"
+  "time"
 
   "github.com/FalconOpsLLC/goexec/pkg/goexec"
+  "github.com/FalconOpsLLC/goexec/pkg/goexec/dce"
+  "github.com/oiweiwei/go-msrpc/dcerpc"
   "github.com/oiweiwei/go-msrpc/midl/uuid"
+  "github.com/oiweiwei/go-msrpc/msrpc/dcom"
+  "github.com/oiweiwei/go-msrpc/msrpc/dcom/oaut"
+  "github.com/oiweiwei/go-msrpc/msrpc/erref/hresult"
+  "github.com/oiweiwei/go-msrpc/msrpc/rrp/winreg/v1"
+  "github.com/rs/zerolog"
 
-  _ "github.com/oiweiwei/go-msrpc/msrpc/erref/hresult"
   _ "github.com/oiweiwei/go-msrpc/msrpc/erref/ntstatus"
   _ "github.com/oiweiwei/go-msrpc/msrpc/erref/win32"
 )
 
 const (
   MethodVisualStudioDTE   = "VisualStudio.DTE:ExecuteCommand"
+  MethodVisualStudioBuild = "VisualStudio.DTE:SolutionBuild.Build"
+  MethodVisualStudioDebug = "VisualStudio.DTE:Debugger.Go"
   VisualStudioDteUuid     = "33ABD590-0400-4FEF-AF98-5F5A8A99CFC3"
   VisualStudioDte2019Uuid = "2E1517DA-87BF-4443-984A-D2BF18F5A908"
+
+  // WinregEndpoint is the remote registry endpoint used to resolve VisualStudioDteProgIds
+  WinregEndpoint = "ncacn_np:[winreg]"
+
+  // VisualStudioDebugTimeout is the default duration to wait for the debugger to start the program
+  VisualStudioDebugTimeout = 2 * time.Minute
+
+  // prjStartActionProgram is the prjStartAction value that starts an external program
+  prjStartActionProgram int32 = 1
+
+  // dbgDesignMode is the dbgDebugMode value of a debugger without debuggee
+  dbgDesignMode int32 = 1
+
+  visualStudioPollInterval = 500 * time.Millisecond
+
+  // visualStudioClsidSize is the buffer size used to read a CLSID value from the registry
+  visualStudioClsidSize = 256
 )
 
-type DcomVisualStudioDte struct {
+var (
+  // VisualStudioDteProgIds are the versioned VisualStudio.DTE ProgIDs (2022, 2019, 2017) resolved to class IDs
+  // through the remote registry, newest first
+  VisualStudioDteProgIds = []string{"VisualStudio.DTE.17.0", "VisualStudio.DTE.16.0", "VisualStudio.DTE.15.0"}
+
+  // VisualStudioDteClasses are the VisualStudio.DTE classes probed by DcomVisualStudio after the resolved ProgIDs, newest first
+  VisualStudioDteClasses = []ScanClass{
+    {"VisualStudio.DTE (2019)", VisualStudioDte2019Uuid},
+    {"VisualStudio.DTE", VisualStudioDteUuid},
+  }
+)
+
+// DcomVisualStudio is a VisualStudio.DTE client. The installed version is detected by activating each of Clsids,
+// then the class of each of VisualStudioDteProgIds, then each of VisualStudioDteClasses until one is registered
+type DcomVisualStudio struct {
   Dispatch
-  // Is2019 indicates that the installation is Visual Studio 2019
-  Is2019 bool
+
+  // Clsids are additional VisualStudio.DTE class IDs probed before the detected classes
+  Clsids []string
+
+  // NoRegistry disables resolving VisualStudioDteProgIds through the remote registry, which requires SMB
+  NoRegistry bool
+}
+
+type DcomVisualStudioDte struct {
+  DcomVisualStudio
   // CommandName is the name of the DTE command to invoke
   CommandName string
   // CommandArgs are the arguments to pass to the command
   CommandArgs string
 }
 
-func (m *DcomVisualStudioDte) Init(ctx context.Context) (err error) {
-  if err = m.Dcom.Init(ctx); err == nil {
-    if m.Is2019 {
-      return m.getDispatch(ctx, uuid.MustParse(VisualStudioDte2019Uuid))
+// DcomVisualStudioSolution executes from a project of a solution opened in Visual Studio
+type DcomVisualStudioSolution struct {
+  DcomVisualStudio
+
+  // Solution is the path of the solution file on the remote host
+  Solution string
+
+  // Project is the name of the project to use. Defaults to the first project of the solution
+  Project string
+}
+
+// DcomVisualStudioBuild executes a pre-build event with Solution.SolutionBuild.Build
+type DcomVisualStudioBuild struct {
+  DcomVisualStudioSolution
+}
+
+// DcomVisualStudioDebug starts a program with Debugger.Go, then detaches the debugger
+type DcomVisualStudioDebug struct {
+  DcomVisualStudioSolution
+
+  // WorkingDirectory is the working directory of the program
+  WorkingDirectory string
+
+  // Timeout is the maximum duration to wait for the debugger to start the program. Defaults to VisualStudioDebugTimeout
+  Timeout time.Duration
+}
+
+func (m *DcomVisualStudio) Init(ctx context.Context) (err error) {
+  log := zerolog.Ctx(ctx)
+
+  if err = m.Dcom.Init(ctx); err != nil {
+    return
+  }
+  classes := make([]ScanClass, 0, len(m.Clsids)+len(VisualStudioDteProgIds)+len(VisualStudioDteClasses))
+
+  for _, c := range m.Clsids {
+    if _, err = uuid.Parse(c); err != nil {
+      return fmt.Errorf("parse CLSID %q: %w", c, err)
+    }
+    classes = append(classes, ScanClass{Name: "VisualStudio.DTE", Clsid: c})
+  }
+  if !m.NoRegistry {
+    resolved, err := m.resolveProgIds(ctx)
+    if err != nil {
+      log.Warn().Err(err).Msg("Failed to resolve VisualStudio.DTE classes from the remote registry")
+    }
+    classes = append(classes, resolved...)
+  }
+  seen := make(map[string]bool)
+
+  for _, class := range append(classes, VisualStudioDteClasses...) {
+    if seen[strings.ToUpper(class.Clsid)] {
+      continue
+    }
+    seen[strings.ToUpper(class.Clsid)] = true
+
+    if err = m.getDispatch(ctx, uuid.MustParse(class.Clsid)); err == nil {
+      log.Info().Str("class", class.Name).Str("clsid", class.Clsid).Msg("Found Visual Studio installation")
+      return
+    }
+    if !isClassNotRegistered(err) {
+      return
+    }
+    log.Debug().Str("class", class.Name).Str("clsid", class.Clsid).Msg("Class is not registered")
+  }
+  return errors.New("no Visual Studio installation found")
+}
+
+// resolveProgIds reads the class ID of each of VisualStudioDteProgIds from HKCR on the remote host.
+// ProgIDs that aren't registered are skipped
+func (m *Dcom) resolveProgIds(ctx context.Context) (classes []ScanClass, err error) {
+  log := zerolog.Ctx(ctx)
+
+  reg := dce.Client{Options: m.Client.Options}
+  reg.Endpoint = WinregEndpoint
+  reg.Filter = ""
+  reg.UseEpm = false
+
+  if err = reg.Parse(ctx); err != nil {
+    return nil, fmt.Errorf("parse winreg client: %w", err)
+  }
+  if err = reg.Connect(ctx); err != nil {
+    return nil, err
+  }
+  defer func() {
+    if closeErr := reg.Close(ctx); closeErr != nil {
+      log.Debug().Err(closeErr).Msg("Failed to close winreg connection")
     }
-    return m.getDispatch(ctx, uuid.MustParse(VisualStudioDteUuid))
+  }()
+
+  wr, err := winreg.NewWinregClient(ctx, reg.Dce(), dcerpc.WithSeal())
+  if err != nil {
+    return nil, fmt.Errorf("create winreg client: %w", err)
+  }
+  root, err := wr.OpenClassesRoot(ctx, &winreg.OpenClassesRootRequest{DesiredAccess: winreg.KeyEnumerateSubKeys})
+  if err != nil {
+    return nil, fmt.Errorf("open HKCR: %w", err)
+  }
+  defer func() { _, _ = wr.BaseRegCloseKey(ctx, &winreg.BaseRegCloseKeyRequest{Key: root.Key}) }()
+
+  for _, progId := range VisualStudioDteProgIds {
+    clsid, err := readDefaultValue(ctx, wr, root.Key, progId+`\CLSID`)
+    if err != nil {
+      log.Debug().Err(err).Str("progId", progId).Msg("ProgID is not registered")
+      continue
+    }
+    if _, err = uuid.Parse(clsid); err != nil {
+      log.Debug().Err(err).Str("progId", progId).Str("clsid", clsid).Msg("ProgID has an invalid CLSID")
+      continue
+    }
+    log.Debug().Str("progId", progId).Str("clsid", clsid).Msg("Resolved ProgID")
+    classes = append(classes, ScanClass{Name: progId, Clsid: clsid})
   }
   return
 }
 
+// readDefaultValue returns the default string value of the subkey of key
+func readDefaultValue(ctx context.Context, wr winreg.WinregClient, key *winreg.Key, subKey string) (value string, err error) {
+  kr, err := wr.BaseRegOpenKey(ctx, &winreg.BaseRegOpenKeyRequest{
+    Key:           key,
+    SubKey:        &winreg.UnicodeString{Buffer: subKey},
+    DesiredAccess: winreg.KeyQueryValue,
+  })
+  if err != nil {
+    return "", fmt.Errorf("open key %q: %w", subKey, err)
+  }
+  defer func() { _, _ = wr.BaseRegCloseKey(ctx, &winreg.BaseRegCloseKeyRequest{Key: kr.ResultKey}) }()
+
+  // The default value has an empty, null-terminated name
+  qv, err := wr.BaseRegQueryValue(ctx, &winreg.BaseRegQueryValueRequest{
+    Key:        kr.ResultKey,
+    ValueName:  &winreg.UnicodeString{Buffer: "\x00", Length: 2, MaximumLength: 2},
+    Data:       make([]byte, visualStudioClsidSize),
+    DataLength: visualStudioClsidSize,
+    Length:     visualStudioClsidSize,
+  })
+  if err != nil {
+    return "", fmt.Errorf("query value of %q: %w", subKey, err)
+  }
+  if qv.Type != winreg.RegString {
+    return "", fmt.Errorf("value of %q has unexpected type %d", subKey, qv.Type)
+  }
+  data := qv.Data
+  if int(qv.Length) < len(data) {
+    data = data[:qv.Length]
+  }
+  v, err := winreg.DecodeValue(qv.Type, data)
+  if err != nil {
+    return "", fmt.Errorf("decode value of %q: %w", subKey, err)
+  }
+  // The CLSID is stored in registry format, i.e. {2E1517DA-87BF-4443-984A-D2BF18F5A908}
+  return strings.Trim(v.(string), "{}"), nil
+}
+
+// quit terminates devenv.exe
+func (m *DcomVisualStudio) quit(ctx context.Context) {
+  log := zerolog.Ctx(ctx)
+
+  q, err := m.callComMethod(ctx, nil, "Quit")
+  if err != nil {
+    log.Warn().Err(err).Msg("Call to Quit() failed")
+    return
+  }
+  log.Info().Int32("return", q.Return).Msg("Quit called")
+}
+
 func (m *DcomVisualStudioDte) Execute(ctx context.Context, execIO *goexec.ExecutionIO) (err error) {
   log := zerolog.Ctx(ctx)
   dteCmd := m.CommandName
@@ -51,15 +256,8 @@ func (m *DcomVisualStudioDte) Execute(ctx context.Context, execIO *goexec.Execut
     dteCmd = "tools.shell"
     dteArgs = execIO.String()
   }
-  defer func() {
-    // Terminate devenv.exe
-    q, err := m.callComMethod(ctx, nil, "Quit")
-    if err != nil {
-      log.Warn().Err(err).Msg("Call to Quit() failed")
-      return
-    }
-    zerolog.Ctx(ctx).Info().Int32("return", q.Return).Msg("Quit called")
-  }()
+  defer m.quit(ctx) // Terminate devenv.exe
+
   log.Info().Str("command", dteCmd).Str("args", dteArgs).Msg("Executing DTE command")
   ir, err := m.callComMethod(ctx, nil, "ExecuteCommand", stringToVariant(dteArgs), stringToVariant(dteCmd))
   if err == nil {
@@ -67,3 +265,193 @@ func (m *DcomVisualStudioDte) Execute(ctx context.Context, execIO *goexec.Execut
   }
   return
 }
+
+// open opens Solution, and returns the IPIDs of the solution and the selected project. The solution is closed by the returned function
+func (m *DcomVisualStudioSolution) open(ctx context.Context) (sol, proj *dcom.IPID, closeFn func(), err error) {
+  log := zerolog.Ctx(ctx).With().Str("solution", m.Solution).Logger()
+
+  if sol, _, err = m.invokeObject(ctx, nil, "Solution", DispatchPropertyGet); err != nil {
+    return nil, nil, nil, fmt.Errorf("get solution: %w", err)
+  }
+  or, err := m.callComMethod(ctx, sol, "Open", stringToVariant(m.Solution))
+  if err != nil {
+    return nil, nil, nil, fmt.Errorf("open solution: %w", err)
+  }
+  if or.Return != 0 {
+    return nil, nil, nil, fmt.Errorf("open solution: %w", hresult.FromCode(uint32(or.Return)))
+  }
+  log.Info().Msg("Opened solution")
+
+  closeFn = func() {
+    if _, err := m.callComMethod(ctx, sol, "Close", newVariant(vtBool, &oaut.Variant_VarUnion{Value: &oaut.Variant_VarUnion_Bool{Bool: variantBool(false)}})); err != nil {
+      log.Warn().Err(err).Msg("Failed to close solution")
+    }
+  }
+  item := newVariant(vtI4, &oaut.Variant_VarUnion{Value: &oaut.Variant_VarUnion_Long{Long: 1}})
+  if m.Project != "" {
+    item = stringToVariant(m.Project)
+  }
+  if proj, _, err = m.invokeObject(ctx, sol, "Projects.Item", DispatchMethod, item); err != nil {
+    closeFn()
+    return nil, nil, nil, fmt.Errorf("get project: %w", err)
+  }
+  return
+}
+
+// setProperty sets the value of the property name in the Properties collection props, and returns a function
+// that restores the original value
+func (m *DcomVisualStudioSolution) setProperty(ctx context.Context, props *dcom.IPID, name string, value *oaut.Variant) (restore func(), err error) {
+  prop, _, err := m.invokeObject(ctx, props, "Item", DispatchMethod, stringToVariant(name))
+  if err != nil {
+    return nil, fmt.Errorf("get property %q: %w", name, err)
+  }
+  gr, err := m.invoke(ctx, prop, "Value", DispatchPropertyGet)
+  if err != nil {
+    return nil, fmt.Errorf("get value of property %q: %w", name, err)
+  }
+  pr, err := m.invoke(ctx, prop, "Value", DispatchPropertyPut, value)
+  if err != nil {
+    return nil, fmt.Errorf("set value of property %q: %w", name, err)
+  }
+  if pr.Return != 0 {
+    return nil, fmt.Errorf("set value of property %q: %w", name, hresult.FromCode(uint32(pr.Return)))
+  }
+  zerolog.Ctx(ctx).Debug().Str("property", name).Msg("Set project property")
+
+  return func() {
+    if gr.VarResult == nil {
+      return
+    }
+    if _, err := m.invoke(ctx, prop, "Value", DispatchPropertyPut, gr.VarResult); err != nil {
+      zerolog.Ctx(ctx).Warn().Err(err).Str("property", name).Msg("Failed to restore project property")
+    }
+  }, nil
+}
+
+// save saves the project, so that it no longer contains the modified properties
+func (m *DcomVisualStudioSolution) save(ctx context.Context, proj *dcom.IPID) {
+  if _, err := m.callComMethod(ctx, proj, "Save"); err != nil {
+    zerolog.Ctx(ctx).Warn().Err(err).Msg("Failed to save project")
+  }
+}
+
+// Execute will set the pre-build event of the project to the command, then build the solution
+func (m *DcomVisualStudioBuild) Execute(ctx context.Context, execIO *goexec.ExecutionIO) (err error) {
+  log := zerolog.Ctx(ctx)
+  defer m.quit(ctx) // Terminate devenv.exe
+
+  sol, proj, closeSolution, err := m.open(ctx)
+  if err != nil {
+    return
+  }
+  defer closeSolution()
+
+  props, _, err := m.invokeObject(ctx, proj, "Properties", DispatchPropertyGet)
+  if err != nil {
+    return fmt.Errorf("get project properties: %w", err)
+  }
+  restore, err := m.setProperty(ctx, props, "PreBuildEvent", stringToVariant(execIO.String()))
+  if err != nil {
+    return
+  }
+  defer func() {
+    restore()
+    m.save(ctx, proj) // The project may be saved before it is built
+  }()
+  log.Info().Msg("Building solution")
+
+  // SolutionBuild.Build(WaitForBuildToFinish)
+  br, err := m.callComMethod(ctx, sol, "SolutionBuild.Build", newVariant(vtBool, &oaut.Variant_VarUnion{Value: &oaut.Variant_VarUnion_Bool{Bool: variantBool(true)}}))
+  if err != nil {
+    return fmt.Errorf("build solution: %w", err)
+  }
+  if br.Return != 0 {
+    return fmt.Errorf("build solution: %w", hresult.FromCode(uint32(br.Return)))
+  }
+  if ir, err := m.invoke(ctx, sol, "SolutionBuild.LastBuildInfo", DispatchPropertyGet); err == nil {
+    log.Info().Any("failed", variantValue(ir.VarResult)).Msg("Solution built")
+  }
+  return
+}
+
+// Execute will set the start action of the project to the command, then start it with the debugger
+func (m *DcomVisualStudioDebug) Execute(ctx context.Context, execIO *goexec.ExecutionIO) (err error) {
+  log := zerolog.Ctx(ctx)
+  defer m.quit(ctx) // Terminate devenv.exe
+
+  _, proj, closeSolution, err := m.open(ctx)
+  if err != nil {
+    return
+  }
+  defer closeSolution()
+
+  props, _, err := m.invokeObject(ctx, proj, "ConfigurationManager.ActiveConfiguration.Properties", DispatchPropertyGet)
+  if err != nil {
+    return fmt.Errorf("get configuration properties: %w", err)
+  }
+  cmdline := execIO.CommandLine()
+  defer m.save(ctx, proj) // Runs after the properties are restored
+
+  for _, p := range []struct {
+    name  string
+    value *oaut.Variant
+  }{
+    {"StartAction", newVariant(vtI4, &oaut.Variant_VarUnion{Value: &oaut.Variant_VarUnion_Long{Long: prjStartActionProgram}})},
+    {"StartProgram", stringToVariant(cmdline[0])},
+    {"StartArguments", stringToVariant(cmdline[1])},
+    {"StartWorkingDirectory", stringToVariant(m.WorkingDirectory)},
+  } {
+    restore, err := m.setProperty(ctx, props, p.name, p.value)
+    if err != nil {
+      return err
+    }
+    defer restore()
+  }
+
+  log.Info().Str("program", cmdline[0]).Msg("Starting debugger")
+
+  // Debugger.Go(WaitForBreakOrEnd)
+  gr, err := m.callComMethod(ctx, nil, "Debugger.Go", newVariant(vtBool, &oaut.Variant_VarUnion{Value: &oaut.Variant_VarUnion_Bool{Bool: variantBool(false)}}))
+  if err != nil {
+    return fmt.Errorf("call Debugger.Go: %w", err)
+  }
+  if gr.Return != 0 {
+    return fmt.Errorf("call Debugger.Go: %w", hresult.FromCode(uint32(gr.Return)))
+  }
+  if err = m.waitForRunMode(ctx); err != nil {
+    return
+  }
+
+  // The program would be terminated with devenv.exe
+  if _, err = m.callComMethod(ctx, nil, "Debugger.DetachAll"); err != nil {
+    return fmt.Errorf("call Debugger.DetachAll: %w", err)
+  }
+  log.Info().Msg("Detached debugger from program")
+  return
+}
+
+// waitForRunMode waits until the debugger is running (or broke into) the program, or Timeout has passed
+func (m *DcomVisualStudioDebug) waitForRunMode(ctx context.Context) error {
+  timeout := m.Timeout
+  if timeout <= 0 {
+    timeout = VisualStudioDebugTimeout
+  }
+  ctx, cancel := context.WithTimeout(ctx, timeout)
+  defer cancel()
+
+  for {
+    ir, err := m.invoke(ctx, nil, "Debugger.CurrentMode", DispatchPropertyGet)
+    if err != nil {
+      return fmt.Errorf("get Debugger.CurrentMode: %w", err)
+    }
+    if mode, ok := variantValue(ir.VarResult).(int32); ok && mode != dbgDesignMode {
+      zerolog.Ctx(ctx).Info().Msg("Debugger started program")
+      return nil
+    }
+    select {
+    case <-ctx.Done():
+      return fmt.Errorf("wait for debugger: %w", ctx.Err())
+    case <-time.After(visualStudioPollInterval):
+    }
+  }
+}
@@ -0,0 +1,163 @@
+package dcomexec
+
+import (
+  "context"
+  "errors"
+  "fmt"
+  "syscall"
+
+  "github.com/oiweiwei/go-msrpc/midl/uuid"
+  "github.com/oiweiwei/go-msrpc/msrpc/dcom"
+  "github.com/oiweiwei/go-msrpc/msrpc/dcom/oaut"
+  "github.com/oiweiwei/go-msrpc/msrpc/erref/hresult"
+  "github.com/rs/zerolog"
+)
+
+const (
+  MethodWordMacro     = "Word:Run"
+  WordApplicationUuid = "000209FF-0000-0000-C000-000000000046"
+
+  // wordMaxRunArgs is the maximum number of macro arguments accepted by Application.Run
+  wordMaxRunArgs = 30
+
+  // wdDoNotSaveChanges is the WdSaveOptions value that discards changes to open documents
+  wdDoNotSaveChanges int32 = 0
+)
+
+type DcomWord struct {
+  Dispatch
+}
+
+type DcomWordMacro struct {
+  DcomWord
+
+  // Document is a local or UNC path of a document to open before running Macro.
+  // Auto macros (i.e. AutoOpen) of the document run when it is opened
+  Document string
+
+  // Macro is the name of the macro to run (i.e. Module1.Main)
+  Macro string
+
+  // Args are the typed arguments of the macro (see parseVariant)
+  Args []string
+
+  NoTerminate bool
+}
+
+// Init will initialize the Word.Application instance
+func (m *DcomWord) Init(ctx context.Context) (err error) {
+  if err = m.Dcom.Init(ctx); err == nil {
+    return m.getDispatch(ctx, uuid.MustParse(WordApplicationUuid))
+  }
+  return
+}
+
+// quit will terminate WINWORD.EXE via Quit(wdDoNotSaveChanges)
+func (m *DcomWord) quit(ctx context.Context) (err error) {
+  log := zerolog.Ctx(ctx)
+  log.Info().
+    Str("call", "Quit").
+    Msg("terminating Word process")
+
+  qr, err := m.callComMethod(ctx, nil, "Quit",
+    newVariant(vtI4, &oaut.Variant_VarUnion{Value: &oaut.Variant_VarUnion_Long{Long: wdDoNotSaveChanges}}))
+  if err != nil {
+    if errors.Is(err, syscall.ECONNRESET) {
+      log.Info().Msg("Word process terminated")
+      return nil
+    }
+    log.Warn().Err(err).Msg("Call Quit failed")
+    return
+  }
+  if qr.Return != 0 {
+    err = hresult.FromCode(uint32(qr.Return))
+    log.Warn().Err(err).Msgf("Call Quit: %d", qr.Return)
+  }
+  return
+}
+
+// open will open Document read-only with Documents.Open, and return the IPID of the document object
+func (m *DcomWord) open(ctx context.Context, document string) (id *dcom.IPID, err error) {
+  falseVariant := newVariant(vtBool, &oaut.Variant_VarUnion{Value: &oaut.Variant_VarUnion_Bool{Bool: variantBool(false)}})
+  trueVariant := newVariant(vtBool, &oaut.Variant_VarUnion{Value: &oaut.Variant_VarUnion_Bool{Bool: variantBool(true)}})
+
+  // Documents.Open(FileName, ConfirmConversions, ReadOnly, AddToRecentFiles) - arguments must be passed in reverse order
+  or, err := m.callComMethod(ctx, nil, "Documents.Open",
+    falseVariant,
+    trueVariant,
+    falseVariant,
+    stringToVariant(document))
+
+  if err != nil {
+    return nil, fmt.Errorf("call Documents.Open: %w", err)
+  }
+  if or.Return != 0 {
+    return nil, fmt.Errorf("call Documents.Open: %w", hresult.FromCode(uint32(or.Return)))
+  }
+  doc, ok := or.VarResult.VarUnion.GetValue().(*oaut.Dispatch)
+  if !ok {
+    return nil, errors.New("call Documents.Open: invalid dispatch object for document")
+  }
+  return doc.InterfacePointer().GetStandardObjectReference().Std.IPID, nil
+}
+
+func (m *DcomWordMacro) Call(ctx context.Context) (err error) {
+  log := zerolog.Ctx(ctx)
+
+  if len(m.Args) > wordMaxRunArgs {
+    return fmt.Errorf("macro accepts at most %d arguments, got %d", wordMaxRunArgs, len(m.Args))
+  }
+  if !m.NoTerminate { // Terminate WINWORD.EXE via Quit(wdDoNotSaveChanges)
+    defer func() {
+      _ = m.quit(ctx)
+    }()
+  }
+  if m.Document != "" {
+    log.Info().Str("document", m.Document).Msg("Opening document")
+
+    doc, err := m.open(ctx, m.Document)
+    if err != nil {
+      return err
+    }
+    log.Info().Msg("Document opened")
+
+    if m.NoTerminate { // Close the document without saving once the macro has run
+      defer func() {
+        if _, err := m.callComMethod(ctx, doc, "Close",
+          newVariant(vtI4, &oaut.Variant_VarUnion{Value: &oaut.Variant_VarUnion_Long{Long: wdDoNotSaveChanges}})); err != nil {
+          log.Warn().Err(err).Msg("Failed to close document")
+        }
+      }()
+    }
+  }
+  if m.Macro == "" {
+    return
+  }
+
+  // Application.Run(MacroName, varg1, ..., varg30) - arguments must be passed in reverse order
+  args := make([]*oaut.Variant, len(m.Args)+1)
+  args[len(args)-1] = stringToVariant(m.Macro)
+
+  for i, arg := range m.Args {
+    v, err := parseVariant(arg)
+    if err != nil {
+      return fmt.Errorf("argument %d: %w", i+1, err)
+    }
+    args[len(args)-2-i] = v
+  }
+  log.Info().
+    Str("call", "Run").
+    Str("macro", m.Macro).
+    Int("args", len(m.Args)).
+    Msg("running Word macro")
+
+  rr, err := m.callComMethod(ctx, nil, "Run", args...)
+  if err != nil {
+    return fmt.Errorf("call Run: %w", err)
+  }
+  if rr.Return != 0 {
+    return fmt.Errorf("call Run: %w", hresult.FromCode(uint32(rr.Return)))
+  }
+  log.Info().Any("result", variantValue(rr.VarResult)).Msg("Run call successful")
+  return
+}
@@ -13,6 +13,12 @@ type OutputProvider interface {
   Clean(ctx context.Context) (err error)
 }
 
+// InputStager uploads a file to the remote host, and removes it on Clean
+type InputStager interface {
+  Stage(ctx context.Context, reader io.Reader) (err error)
+  Clean(ctx context.Context) (err error)
+}
+
 type ExecutionIO struct {
   Cleaner
 
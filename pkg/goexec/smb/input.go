@@ -6,7 +6,7 @@ import (
   "github.com/FalconOpsLLC/goexec/pkg/goexec"
   "io"
   "os"
-  "path"
+  "path/filepath"
   "strings"
 )
 
@@ -25,10 +25,15 @@ type FileStager struct {
 
 func (o *FileStager) Stage(ctx context.Context, reader io.Reader) (err error) {
 
-  o.relativePath = path.Join(
-    strings.ReplaceAll(pathPrefix.ReplaceAllString(o.SharePath, ""), `\`, "/"),
-    strings.ReplaceAll(pathPrefix.ReplaceAllString(o.File, ""), `\`, "/"),
-  )
+  shp := pathPrefix.ReplaceAllString(strings.ToLower(strings.ReplaceAll(o.SharePath, `\`, "/")), "")
+  fp := pathPrefix.ReplaceAllString(strings.ToLower(strings.ReplaceAll(o.File, `\`, "/")), "")
+
+  if o.relativePath, err = filepath.Rel(shp, fp); err != nil {
+    return
+  }
+  if strings.HasPrefix(o.relativePath, "..") {
+    return fmt.Errorf("stage file %q is outside of share path %q", o.File, o.SharePath)
+  }
 
   if o.ForceReconnect || !o.Client.connected {
     err = o.Client.Connect(ctx)
@@ -45,22 +50,21 @@ func (o *FileStager) Stage(ctx context.Context, reader io.Reader) (err error) {
     }
   }
 
-  writer, err := o.Client.mount.OpenFile(o.relativePath, os.O_WRONLY, 0644)
+  writer, err := o.Client.mount.OpenFile(o.relativePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
   if err != nil {
     return fmt.Errorf("open remote file for writing: %w", err)
   }
 
-  if _, err = io.Copy(writer, reader); err != nil {
-    return
-  }
-
-  o.AddCleaners(func(_ context.Context) error { return writer.Close() })
-
   if o.DeleteStage {
     o.AddCleaners(func(_ context.Context) error {
       return o.Client.mount.Remove(o.relativePath)
     })
   }
 
+  // The file is closed right away so that it can be loaded on the remote host
+  _, err = io.Copy(writer, reader)
+  if ce := writer.Close(); err == nil && ce != nil {
+    err = fmt.Errorf("close remote file: %w", ce)
+  }
   return
 }
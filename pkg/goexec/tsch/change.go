@@ -2,13 +2,12 @@ package tschexec
 
 import (
   "context"
-  "encoding/xml"
   "fmt"
   "github.com/FalconOpsLLC/goexec/pkg/goexec"
   "github.com/FalconOpsLLC/goexec/pkg/goexec/tsch/task"
   "github.com/oiweiwei/go-msrpc/msrpc/tsch/itaskschedulerservice/v1"
   "github.com/rs/zerolog"
-  "regexp"
+  "io"
   "time"
 
   _ "github.com/oiweiwei/go-msrpc/msrpc/erref/ntstatus"
@@ -32,6 +31,17 @@ type TschChange struct {
   NoStart          bool
   NoRevert         bool
   WaitTime         time.Duration
+
+  // ReplaceAction is the 1-based index of an existing action to replace. If zero, a new action is appended
+  ReplaceAction int
+  // ActionId is the id attribute of an existing action to replace
+  ActionId string
+  // DisableTriggers temporarily disables every trigger of the task so that it only runs on demand
+  DisableTriggers bool
+
+  // Diff writes the original and patched task definitions to Out as a unified diff, without registering the task
+  Diff bool
+  Out  io.Writer
 }
 
 func (m *TschChange) Execute(ctx context.Context, execIO *goexec.ExecutionIO) (err error) {
@@ -59,34 +69,37 @@ func (m *TschChange) Execute(ctx context.Context, execIO *goexec.ExecutionIO) (e
   log.Info().Msg("Successfully retrieved existing task definition")
   log.Debug().Str("xml", retrieveResponse.XML).Msg("Got task definition")
 
-  tk := task.Task{}
-
-  enc := regexp.MustCompile(`(?i)^<\?xml .*?\?>`)
-  tkStr := enc.ReplaceAllString(retrieveResponse.XML, `<?xml version="1.0" encoding="utf-8"?>`)
-
-  if err = xml.Unmarshal([]byte(tkStr), &tk); err != nil {
-    log.Error().Err(err).Msg("Failed to unmarshal task XML")
-
-    return fmt.Errorf("unmarshal task XML: %w", err)
-  }
-
   cmd := execIO.CommandLine()
 
-  tk.Actions.Exec = append(tk.Actions.Exec, task.ExecAction{
-    Command:          cmd[0],
-    Arguments:        cmd[1],
-    WorkingDirectory: m.WorkingDirectory,
-  })
+  patch := &execPatch{
+    Action: &task.ExecAction{
+      Command:          cmd[0],
+      Arguments:        cmd[1],
+      WorkingDirectory: m.WorkingDirectory,
+    },
+    ReplaceIndex:    m.ReplaceAction,
+    ReplaceId:       m.ActionId,
+    DisableTriggers: m.DisableTriggers,
+  }
 
-  doc, err := xml.Marshal(tk)
+  // Patch the original definition in place so that unknown elements, namespaces and formatting are preserved
+  taskXml, err := patch.apply(retrieveResponse.XML)
 
   if err != nil {
-    log.Error().Err(err).Msg("failed to marshal task XML")
-    return fmt.Errorf("marshal task: %w", err)
+    log.Error().Err(err).Msg("Failed to patch task XML")
+    return fmt.Errorf("patch task XML: %w", err)
   }
+  log.Debug().Str("xml", taskXml).Msg("Patched task definition")
 
-  taskXml := TaskXmlHeader + string(doc)
-  log.Debug().Str("xml", taskXml).Msg("Serialized new task")
+  if m.Diff {
+    if m.Out != nil {
+      if _, err = io.WriteString(m.Out, diffLines(retrieveResponse.XML, taskXml, m.TaskPath)); err != nil {
+        return fmt.Errorf("write diff: %w", err)
+      }
+    }
+    log.Info().Msg("Diff mode enabled, task definition was not modified")
+    return
+  }
 
   registerResponse, err := m.tsch.RegisterTask(ctx, &itaskschedulerservice.RegisterTaskRequest{
     Path:  m.TaskPath,
@@ -126,6 +139,25 @@ func (m *TschChange) Execute(ctx context.Context, execIO *goexec.ExecutionIO) (e
   }
   log.Info().Msg("Successfully updated task definition")
 
+  if m.SDDL != "" {
+    originalSddl, err := m.getSecurity(ctx, m.TaskPath)
+    if err != nil {
+      log.Error().Err(err).Msg("Failed to fetch original task security descriptor")
+      return fmt.Errorf("fetch task security descriptor: %w", err)
+    }
+    log.Debug().Str("sddl", originalSddl).Msg("Fetched original task security descriptor")
+
+    if err = m.setSecurity(ctx, m.TaskPath, m.SDDL); err != nil {
+      return err
+    }
+
+    if !m.NoRevert {
+      m.AddCleaners(func(ctxInner context.Context) error {
+        return m.setSecurity(ctxInner, m.TaskPath, originalSddl)
+      })
+    }
+  }
+
   if !m.NoStart {
 
     runResponse, err := m.tsch.Run(ctx, &itaskschedulerservice.RunRequest{
@@ -100,11 +100,20 @@ func (m *TschCreate) Execute(ctx context.Context, execIO *goexec.ExecutionIO) (e
              }
           */
         }
-        return m.deleteTask(ctxInner, path)
+        if err := m.deleteTask(ctxInner, path); err != nil {
+          return err
+        }
+        return m.deleteFolders(ctxInner)
       })
 
     } else {
       log.Info().Time("when", stopTime).Msg("Task is scheduled to delete")
+
+      // A folder can't be deleted while it contains the task, which is only removed by
+      // the Task Scheduler once it has expired, so created folders are left behind
+      if len(m.createdFolders) > 0 {
+        log.Warn().Strs("folders", m.createdFolders).Msg("Created task folders will not be removed without --call-delete")
+      }
     }
   }
   return
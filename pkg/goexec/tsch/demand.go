@@ -52,7 +52,10 @@ func (m *TschDemand) Execute(ctx context.Context, execIO *goexec.ExecutionIO) (e
 
   if !m.NoDelete {
     m.AddCleaners(func(ctxInner context.Context) error {
-      return m.deleteTask(ctxInner, path)
+      if err := m.deleteTask(ctxInner, path); err != nil {
+        return err
+      }
+      return m.deleteFolders(ctxInner)
     })
   }
 
@@ -18,6 +18,11 @@ import (
 
 const (
   ModuleName = "TSCH"
+
+  // ErrorAlreadyExists is returned by SchRpcCreateFolder when the folder exists (HRESULT_FROM_WIN32(ERROR_ALREADY_EXISTS))
+  ErrorAlreadyExists uint32 = 0x800700b7
+
+  SecurityInformationDacl uint32 = 0x4
 )
 
 type Tsch struct {
@@ -29,6 +34,11 @@ type Tsch struct {
   TaskPath  string
   UserSid   string
   NotHidden bool
+
+  // SDDL is the security descriptor applied to the task and to any folders created for it
+  SDDL string
+
+  createdFolders []string
 }
 
 type registerOptions struct {
@@ -137,11 +147,15 @@ func (m *Tsch) registerTask(ctx context.Context, opts *registerOptions, in *goex
 
   log.Debug().Str("content", taskXml).Msg("Generated task XML")
 
+  if err = m.createParentFolders(ctx); err != nil {
+    return "", err
+  }
+
   registerResponse, err := m.tsch.RegisterTask(ctx, &itaskschedulerservice.RegisterTaskRequest{
     Path:       m.TaskPath,
     XML:        taskXml,
     Flags:      0, // FEATURE: dynamic
-    SDDL:       "",
+    SDDL:       m.SDDL,
     LogonType:  0, // FEATURE: dynamic
     CredsCount: 0,
     Creds:      nil,
@@ -174,3 +188,92 @@ func (m *Tsch) deleteTask(ctx context.Context, taskPath string) (err error) {
 
   return
 }
+
+// createParentFolders calls SchRpcCreateFolder for each parent folder of the task path.
+// Folders that didn't already exist are noted so that they can be removed by deleteFolders
+func (m *Tsch) createParentFolders(ctx context.Context) (err error) {
+
+  log := zerolog.Ctx(ctx)
+
+  parts := strings.Split(strings.Trim(m.TaskPath, `\`), `\`)
+
+  for i := 1; i < len(parts); i++ {
+    folder := `\` + strings.Join(parts[:i], `\`)
+
+    createResponse, err := m.tsch.CreateFolder(ctx, &itaskschedulerservice.CreateFolderRequest{
+      Path: folder,
+      SDDL: m.SDDL,
+    })
+
+    if createResponse != nil && uint32(createResponse.Return) == ErrorAlreadyExists {
+      log.Debug().Str("folder", folder).Msg("Task folder already exists")
+      continue
+    }
+    if err != nil {
+      log.Error().Err(err).Str("folder", folder).Msg("Failed to create task folder")
+      return fmt.Errorf("create folder: %w", err)
+    }
+
+    log.Info().Str("folder", folder).Msg("Created task folder")
+    m.createdFolders = append(m.createdFolders, folder)
+  }
+  return
+}
+
+// deleteFolders removes the folders created by createParentFolders, starting with the deepest
+func (m *Tsch) deleteFolders(ctx context.Context) (err error) {
+
+  log := zerolog.Ctx(ctx)
+
+  for i := len(m.createdFolders) - 1; i >= 0; i-- {
+    folder := m.createdFolders[i]
+
+    _, err := m.tsch.Delete(ctx, &itaskschedulerservice.DeleteRequest{
+      Path: folder,
+    })
+
+    if err != nil {
+      log.Error().Err(err).Str("folder", folder).Msg("Failed to delete task folder")
+      return fmt.Errorf("delete folder: %w", err)
+    }
+
+    log.Info().Str("folder", folder).Msg("Task folder deleted")
+  }
+  m.createdFolders = nil
+  return
+}
+
+// setSecurity calls SchRpcSetSecurity to apply the provided security descriptor to a task
+func (m *Tsch) setSecurity(ctx context.Context, taskPath, sddl string) (err error) {
+
+  log := zerolog.Ctx(ctx).With().
+    Str("path", taskPath).Logger()
+
+  _, err = m.tsch.SetSecurity(ctx, &itaskschedulerservice.SetSecurityRequest{
+    Path:  taskPath,
+    SDDL:  sddl,
+    Flags: 0, // TASK_DONT_ADD_PRINCIPAL_ACE (0x10) is the only defined flag
+  })
+
+  if err != nil {
+    log.Error().Err(err).Msg("Failed to set task security descriptor")
+    return fmt.Errorf("set security: %w", err)
+  }
+
+  log.Info().Str("sddl", sddl).Msg("Task security descriptor updated")
+  return
+}
+
+// getSecurity calls SchRpcGetSecurity to fetch the DACL of a task in SDDL format
+func (m *Tsch) getSecurity(ctx context.Context, taskPath string) (sddl string, err error) {
+
+  getResponse, err := m.tsch.GetSecurity(ctx, &itaskschedulerservice.GetSecurityRequest{
+    Path:                taskPath,
+    SecurityInformation: SecurityInformationDacl,
+  })
+
+  if err != nil {
+    return "", fmt.Errorf("get security: %w", err)
+  }
+  return getResponse.SDDL, nil
+}
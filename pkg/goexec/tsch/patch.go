@@ -0,0 +1,396 @@
+package tschexec
+
+import (
+  "encoding/xml"
+  "errors"
+  "fmt"
+  "io"
+  "sort"
+  "strings"
+
+  "github.com/FalconOpsLLC/goexec/pkg/goexec/tsch/task"
+)
+
+// xmlNode describes the location of an element within a raw XML document
+type xmlNode struct {
+  Prefix string // Namespace prefix of the element, if any
+  Name   string // Local name of the element
+  Attr   []xml.Attr
+
+  Start int64 // Offset of the start tag
+  Inner int64 // Offset immediately following the start tag
+  Close int64 // Offset of the end tag. Equal to End for self-closing elements
+  End   int64 // Offset immediately following the end tag
+
+  // Indent is the whitespace immediately preceding the element
+  Indent   string
+  Children []*xmlNode
+}
+
+// xmlEdit replaces the range [Start, End) of a document with Text
+type xmlEdit struct {
+  Start, End int64
+  Text       string
+}
+
+// execPatch describes the modifications made to a task definition by TschChange
+type execPatch struct {
+  // Action is the new Exec action
+  Action *task.ExecAction
+  // ReplaceIndex is the 1-based index of the action to replace. If zero, Action is appended
+  ReplaceIndex int
+  // ReplaceId is the id attribute of the action to replace. If empty, ReplaceIndex is used
+  ReplaceId string
+  // DisableTriggers sets Enabled to false for every trigger of the task
+  DisableTriggers bool
+}
+
+// parseXmlTree locates each element of the provided XML document without modifying or re-encoding it
+func parseXmlTree(doc string) (root *xmlNode, err error) {
+
+  dec := xml.NewDecoder(strings.NewReader(doc))
+  dec.Strict = false
+
+  // The document was already decoded by the RPC layer; ignore the declared encoding (usually UTF-16)
+  dec.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+    return input, nil
+  }
+
+  var stack []*xmlNode
+  var lastText string
+
+  for {
+    start := dec.InputOffset()
+    tok, err := dec.RawToken()
+
+    if errors.Is(err, io.EOF) {
+      break
+    }
+    if err != nil {
+      return nil, fmt.Errorf("parse task XML: %w", err)
+    }
+    end := dec.InputOffset()
+
+    switch t := tok.(type) {
+    case xml.StartElement:
+      n := &xmlNode{
+        Prefix: t.Name.Space,
+        Name:   t.Name.Local,
+        Attr:   t.Attr,
+        Start:  start,
+        Inner:  end,
+        Indent: lastText,
+      }
+      if l := len(stack); l > 0 {
+        stack[l-1].Children = append(stack[l-1].Children, n)
+      } else if root == nil {
+        root = n
+      }
+      stack = append(stack, n)
+      lastText = ""
+
+    case xml.EndElement:
+      if l := len(stack); l > 0 {
+        stack[l-1].Close = start
+        stack[l-1].End = end
+        stack = stack[:l-1]
+      }
+      lastText = ""
+
+    case xml.CharData:
+      if strings.TrimSpace(string(t)) == "" {
+        lastText = doc[start:end] // The decoder normalizes line endings, so the raw text is used
+      } else {
+        lastText = ""
+      }
+    }
+  }
+
+  if root == nil || len(stack) > 0 {
+    return nil, errors.New("parse task XML: unexpected end of document")
+  }
+  return
+}
+
+// child returns the first child element with the provided local name
+func (n *xmlNode) child(name string) *xmlNode {
+  for _, c := range n.Children {
+    if c.Name == name {
+      return c
+    }
+  }
+  return nil
+}
+
+// attr returns the value of the attribute with the provided local name
+func (n *xmlNode) attr(name string) string {
+  for _, a := range n.Attr {
+    if a.Name.Local == name {
+      return a.Value
+    }
+  }
+  return ""
+}
+
+// qname returns the qualified name of a new element using the namespace prefix of n
+func (n *xmlNode) qname(local string) string {
+  if n.Prefix != "" {
+    return n.Prefix + ":" + local
+  }
+  return local
+}
+
+// childIndent guesses the whitespace that should precede a new child element
+func (n *xmlNode) childIndent() string {
+  if len(n.Children) > 0 {
+    return n.Children[0].Indent
+  }
+  return ""
+}
+
+// appendChild creates an edit that inserts content as the last child of n
+func (n *xmlNode) appendChild(doc string, content string) xmlEdit {
+  if n.Close == n.End { // Self-closing element
+    tag := strings.TrimSpace(strings.TrimSuffix(doc[n.Start:n.End], "/>"))
+    return xmlEdit{Start: n.Start, End: n.End, Text: tag + ">" + content + "</" + n.qname(n.Name) + ">"}
+  }
+  at := n.Inner
+  if l := len(n.Children); l > 0 {
+    at = n.Children[l-1].End
+  }
+  return xmlEdit{Start: at, End: at, Text: n.childIndent() + content}
+}
+
+// applyEdits applies non-overlapping edits to the document
+func applyEdits(doc string, edits []xmlEdit) string {
+  sort.Slice(edits, func(i, j int) bool {
+    return edits[i].Start > edits[j].Start
+  })
+  for _, e := range edits {
+    doc = doc[:e.Start] + e.Text + doc[e.End:]
+  }
+  return doc
+}
+
+// marshalExecAction serializes an <Exec> action using the provided namespace prefix
+func marshalExecAction(prefix string, action *task.ExecAction) (string, error) {
+
+  name := (&xmlNode{Prefix: prefix}).qname
+
+  b := new(strings.Builder)
+  b.WriteString("<" + name("Exec"))
+
+  if action.Id != "" {
+    b.WriteString(` id="`)
+    if err := xml.EscapeText(b, []byte(action.Id)); err != nil {
+      return "", err
+    }
+    b.WriteString(`"`)
+  }
+  b.WriteString(">")
+
+  for _, el := range []struct {
+    local, value string
+    required     bool
+  }{
+    {"Command", action.Command, true},
+    {"Arguments", action.Arguments, false},
+    {"WorkingDirectory", action.WorkingDirectory, false},
+  } {
+    if el.value == "" && !el.required {
+      continue
+    }
+    b.WriteString("<" + name(el.local) + ">")
+    if err := xml.EscapeText(b, []byte(el.value)); err != nil {
+      return "", err
+    }
+    b.WriteString("</" + name(el.local) + ">")
+  }
+  b.WriteString("</" + name("Exec") + ">")
+
+  return b.String(), nil
+}
+
+// apply patches the task XML document. Only the affected nodes are rewritten;
+// the remainder of the document is preserved byte-for-byte.
+func (p *execPatch) apply(doc string) (string, error) {
+
+  root, err := parseXmlTree(doc)
+  if err != nil {
+    return "", err
+  }
+  actions := root.child("Actions")
+  if actions == nil {
+    return "", errors.New("patch task XML: Actions element not found")
+  }
+
+  var edits []xmlEdit
+  action := *p.Action
+
+  if p.ReplaceIndex != 0 || p.ReplaceId != "" {
+    var target *xmlNode
+
+    if p.ReplaceId != "" {
+      for _, c := range actions.Children {
+        if c.attr("id") == p.ReplaceId {
+          target = c
+          break
+        }
+      }
+      if target == nil {
+        return "", fmt.Errorf("patch task XML: action with id %q not found", p.ReplaceId)
+      }
+    } else if p.ReplaceIndex < 1 || p.ReplaceIndex > len(actions.Children) {
+      return "", fmt.Errorf("patch task XML: action index %d out of range (task has %d actions)", p.ReplaceIndex, len(actions.Children))
+    } else {
+      target = actions.Children[p.ReplaceIndex-1]
+    }
+
+    // Carry over the identity and working directory of the original action
+    if action.Id == "" {
+      action.Id = target.attr("id")
+    }
+    if wd := target.child("WorkingDirectory"); wd != nil && target.Name == "Exec" && action.WorkingDirectory == "" {
+      action.WorkingDirectory = xmlText(doc, wd)
+    }
+    node, err := marshalExecAction(target.Prefix, &action)
+    if err != nil {
+      return "", fmt.Errorf("marshal exec action: %w", err)
+    }
+    edits = append(edits, xmlEdit{Start: target.Start, End: target.End, Text: node})
+
+  } else {
+    node, err := marshalExecAction(actions.Prefix, &action)
+    if err != nil {
+      return "", fmt.Errorf("marshal exec action: %w", err)
+    }
+    edits = append(edits, actions.appendChild(doc, node))
+  }
+
+  if p.DisableTriggers {
+    if triggers := root.child("Triggers"); triggers != nil {
+      for _, tr := range triggers.Children {
+        edits = append(edits, disableTrigger(doc, tr))
+      }
+    }
+  }
+
+  return applyEdits(doc, edits), nil
+}
+
+// disableTrigger creates an edit that sets the Enabled element of a trigger to false
+func disableTrigger(doc string, tr *xmlNode) xmlEdit {
+
+  if en := tr.child("Enabled"); en != nil {
+    if en.Close == en.End {
+      return xmlEdit{Start: en.Start, End: en.End, Text: "<" + en.qname("Enabled") + ">false</" + en.qname("Enabled") + ">"}
+    }
+    return xmlEdit{Start: en.Inner, End: en.Close, Text: "false"}
+  }
+  node := "<" + tr.qname("Enabled") + ">false</" + tr.qname("Enabled") + ">"
+
+  // Enabled must follow StartBoundary and EndBoundary, and precede every other element
+  var after *xmlNode
+  for _, c := range tr.Children {
+    if c.Name == "StartBoundary" || c.Name == "EndBoundary" {
+      after = c
+    }
+  }
+  switch {
+  case after != nil:
+    return xmlEdit{Start: after.End, End: after.End, Text: after.Indent + node}
+  case len(tr.Children) > 0:
+    return xmlEdit{Start: tr.Inner, End: tr.Inner, Text: tr.childIndent() + node}
+  }
+  return tr.appendChild(doc, node)
+}
+
+// xmlText returns the unescaped character data of a simple element
+func xmlText(doc string, n *xmlNode) (s string) {
+  _ = xml.Unmarshal([]byte("<v>"+doc[n.Inner:n.Close]+"</v>"), &s)
+  return
+}
+
+// diffLines produces a unified diff of two documents
+func diffLines(before, after, name string) string {
+  a := strings.Split(before, "\n")
+  b := strings.Split(after, "\n")
+
+  // Longest common subsequence of lines; task definitions are small enough for the quadratic approach
+  lcs := make([][]int, len(a)+1)
+  for i := range lcs {
+    lcs[i] = make([]int, len(b)+1)
+  }
+  for i := len(a) - 1; i >= 0; i-- {
+    for j := len(b) - 1; j >= 0; j-- {
+      if a[i] == b[j] {
+        lcs[i][j] = lcs[i+1][j+1] + 1
+      } else {
+        lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+      }
+    }
+  }
+
+  type line struct {
+    op   byte
+    text string
+    ai   int // Line index in a
+    bi   int // Line index in b
+  }
+  var lines []line
+
+  for i, j := 0, 0; i < len(a) || j < len(b); {
+    switch {
+    case i < len(a) && j < len(b) && a[i] == b[j]:
+      lines = append(lines, line{' ', a[i], i, j})
+      i++
+      j++
+    case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+      lines = append(lines, line{'-', a[i], i, j})
+      i++
+    default:
+      lines = append(lines, line{'+', b[j], i, j})
+      j++
+    }
+  }
+
+  const ctxLines = 3
+
+  out := new(strings.Builder)
+  _, _ = fmt.Fprintf(out, "--- %s (original)\n+++ %s (modified)\n", name, name)
+
+  for k := 0; k < len(lines); {
+    if lines[k].op == ' ' {
+      k++
+      continue
+    }
+    // Extend the hunk until there are more than 2*ctxLines unchanged lines in a row
+    from := max(k-ctxLines, 0)
+    to, same := k, 0
+    for ; to < len(lines) && same <= 2*ctxLines; to++ {
+      if lines[to].op == ' ' {
+        same++
+      } else {
+        same = 0
+      }
+    }
+    to -= max(same-ctxLines, 0)
+
+    var aLen, bLen int
+    for _, l := range lines[from:to] {
+      if l.op != '+' {
+        aLen++
+      }
+      if l.op != '-' {
+        bLen++
+      }
+    }
+    _, _ = fmt.Fprintf(out, "@@ -%d,%d +%d,%d @@\n", lines[from].ai+1, aLen, lines[from].bi+1, bLen)
+
+    for _, l := range lines[from:to] {
+      out.WriteString(string(l.op) + strings.TrimSuffix(l.text, "\r") + "\n")
+    }
+    k = to
+  }
+  return out.String()
+}
@@ -0,0 +1,209 @@
+package tschexec
+
+import (
+  "strings"
+  "testing"
+
+  "github.com/FalconOpsLLC/goexec/pkg/goexec/tsch/task"
+)
+
+const patchTestXml = `<?xml version="1.0" encoding="UTF-16"?>
+<Task version="1.2" xmlns="http://schemas.microsoft.com/windows/2004/02/mit/task">
+  <Triggers>
+    <TimeTrigger>
+      <StartBoundary>2025-01-01T00:00:00</StartBoundary>
+      <Enabled>true</Enabled>
+    </TimeTrigger>
+    <LogonTrigger>
+      <UserId>S-1-5-18</UserId>
+    </LogonTrigger>
+  </Triggers>
+  <Actions Context="Author">
+    <Exec id="first">
+      <Command>a.exe</Command>
+      <WorkingDirectory>C:\Work</WorkingDirectory>
+    </Exec>
+    <Exec>
+      <Command>b.exe</Command>
+    </Exec>
+  </Actions>
+</Task>`
+
+func TestExecPatchApply(t *testing.T) {
+  action := &task.ExecAction{Command: "cmd.exe", Arguments: "/c whoami"}
+
+  replaced := `<Exec id="first"><Command>cmd.exe</Command><Arguments>/c whoami</Arguments><WorkingDirectory>C:\Work</WorkingDirectory></Exec>`
+  original := `<Exec id="first">
+      <Command>a.exe</Command>
+      <WorkingDirectory>C:\Work</WorkingDirectory>
+    </Exec>`
+
+  tests := []struct {
+    name  string
+    doc   string
+    patch execPatch
+    want  string
+    err   string
+  }{
+    {
+      name:  "append",
+      doc:   patchTestXml,
+      patch: execPatch{Action: action},
+      want: strings.Replace(patchTestXml, "</Exec>\n  </Actions>",
+        "</Exec>\n    <Exec><Command>cmd.exe</Command><Arguments>/c whoami</Arguments></Exec>\n  </Actions>", 1),
+    },
+    {
+      name:  "replace by index",
+      doc:   patchTestXml,
+      patch: execPatch{Action: action, ReplaceIndex: 1},
+      want:  strings.Replace(patchTestXml, original, replaced, 1),
+    },
+    {
+      name:  "replace by id",
+      doc:   patchTestXml,
+      patch: execPatch{Action: action, ReplaceId: "first"},
+      want:  strings.Replace(patchTestXml, original, replaced, 1),
+    },
+    {
+      name:  "replace by index without id",
+      doc:   patchTestXml,
+      patch: execPatch{Action: action, ReplaceIndex: 2},
+      want: strings.Replace(patchTestXml, "<Exec>\n      <Command>b.exe</Command>\n    </Exec>",
+        "<Exec><Command>cmd.exe</Command><Arguments>/c whoami</Arguments></Exec>", 1),
+    },
+    {
+      name:  "disable triggers",
+      doc:   patchTestXml,
+      patch: execPatch{Action: action, ReplaceIndex: 1, DisableTriggers: true},
+      want: strings.NewReplacer(
+        original, replaced,
+        "<Enabled>true</Enabled>", "<Enabled>false</Enabled>",
+        "<LogonTrigger>\n", "<LogonTrigger>\n      <Enabled>false</Enabled>\n",
+      ).Replace(patchTestXml),
+    },
+    {
+      name:  "disable triggers after boundaries",
+      doc:   `<Task><Triggers><TimeTrigger><StartBoundary>x</StartBoundary><Repetition/></TimeTrigger><BootTrigger/></Triggers><Actions/></Task>`,
+      patch: execPatch{Action: &task.ExecAction{Command: "c"}, DisableTriggers: true},
+      want:  `<Task><Triggers><TimeTrigger><StartBoundary>x</StartBoundary><Enabled>false</Enabled><Repetition/></TimeTrigger><BootTrigger><Enabled>false</Enabled></BootTrigger></Triggers><Actions><Exec><Command>c</Command></Exec></Actions></Task>`,
+    },
+    {
+      name:  "self-closing actions",
+      doc:   `<Task><Actions Context="Author" /></Task>`,
+      patch: execPatch{Action: action},
+      want:  `<Task><Actions Context="Author"><Exec><Command>cmd.exe</Command><Arguments>/c whoami</Arguments></Exec></Actions></Task>`,
+    },
+    {
+      name:  "namespace prefix",
+      doc:   `<t:Task xmlns:t="urn:task"><t:Actions><t:Exec><t:Command>a.exe</t:Command></t:Exec></t:Actions></t:Task>`,
+      patch: execPatch{Action: &task.ExecAction{Command: "c"}},
+      want:  `<t:Task xmlns:t="urn:task"><t:Actions><t:Exec><t:Command>a.exe</t:Command></t:Exec><t:Exec><t:Command>c</t:Command></t:Exec></t:Actions></t:Task>`,
+    },
+    {
+      name:  "index out of range",
+      doc:   patchTestXml,
+      patch: execPatch{Action: action, ReplaceIndex: 3},
+      err:   "action index 3 out of range (task has 2 actions)",
+    },
+    {
+      name:  "unknown id",
+      doc:   patchTestXml,
+      patch: execPatch{Action: action, ReplaceId: "missing"},
+      err:   `action with id "missing" not found`,
+    },
+    {
+      name:  "missing actions",
+      doc:   `<Task><Triggers/></Task>`,
+      patch: execPatch{Action: action},
+      err:   "Actions element not found",
+    },
+  }
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      got, err := tt.patch.apply(tt.doc)
+
+      if tt.err != "" {
+        if err == nil || !strings.Contains(err.Error(), tt.err) {
+          t.Fatalf("got error %v, want %q", err, tt.err)
+        }
+        return
+      }
+      if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+      }
+      if got != tt.want {
+        t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+      }
+    })
+  }
+}
+
+func TestParseXmlTree(t *testing.T) {
+  doc := `<A><B x="1">text</B><C/></A>`
+
+  root, err := parseXmlTree(doc)
+  if err != nil {
+    t.Fatalf("unexpected error: %v", err)
+  }
+  b, c := root.child("B"), root.child("C")
+  if b == nil || c == nil {
+    t.Fatal("child elements not found")
+  }
+  if got := doc[b.Start:b.End]; got != `<B x="1">text</B>` {
+    t.Errorf("got B element %q", got)
+  }
+  if got := doc[b.Inner:b.Close]; got != "text" {
+    t.Errorf("got B content %q", got)
+  }
+  if b.attr("x") != "1" {
+    t.Errorf("got B attribute %q", b.attr("x"))
+  }
+  if c.Close != c.End || doc[c.Start:c.End] != "<C/>" {
+    t.Errorf("got C element %q (close %d, end %d)", doc[c.Start:c.End], c.Close, c.End)
+  }
+  if _, err = parseXmlTree("<A><B>"); err == nil {
+    t.Error("expected error for unterminated document")
+  }
+}
+
+func TestDiffLines(t *testing.T) {
+  tests := []struct {
+    name          string
+    before, after string
+    want          string
+  }{
+    {
+      name:   "change",
+      before: "a\nb\nc",
+      after:  "a\nx\nc",
+      want:   "@@ -1,3 +1,3 @@\n a\n-b\n+x\n c\n",
+    },
+    {
+      name:   "insert with context",
+      before: "1\n2\n3\n4\n5\n6\n7\n8",
+      after:  "1\n2\n3\n4\n4.5\n5\n6\n7\n8",
+      want:   "@@ -2,6 +2,7 @@\n 2\n 3\n 4\n+4.5\n 5\n 6\n 7\n",
+    },
+    {
+      name:   "separate hunks",
+      before: "a\n1\n2\n3\n4\n5\n6\n7\n8\nb",
+      after:  "A\n1\n2\n3\n4\n5\n6\n7\n8\nB",
+      want:   "@@ -1,4 +1,4 @@\n-a\n+A\n 1\n 2\n 3\n@@ -7,4 +7,4 @@\n 6\n 7\n 8\n-b\n+B\n",
+    },
+    {
+      name:   "unchanged",
+      before: "a\nb",
+      after:  "a\nb",
+      want:   "",
+    },
+  }
+  for _, tt := range tests {
+    t.Run(tt.name, func(t *testing.T) {
+      want := "--- task (original)\n+++ task (modified)\n" + tt.want
+
+      if got := diffLines(tt.before, tt.after, "task"); got != want {
+        t.Errorf("got:\n%s\nwant:\n%s", got, want)
+      }
+    })
+  }
+}
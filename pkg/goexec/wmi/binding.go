@@ -0,0 +1,142 @@
+package wmiexec
+
+import (
+  "fmt"
+  "strings"
+
+  "github.com/oiweiwei/go-msrpc/dcerpc"
+  "github.com/oiweiwei/go-msrpc/msrpc/dcom"
+)
+
+var (
+  // DefaultProtocols are the OXID binding protocol sequences used when none are specified
+  DefaultProtocols = []string{"tcp", "np"}
+
+  bindingProtocols = map[string]struct {
+    sequence uint16
+    name     dcerpc.ProtocolSequence
+  }{
+    "tcp":          {ProtocolSequenceRPC, dcerpc.ProtocolSequenceIPTCP},
+    "ncacn_ip_tcp": {ProtocolSequenceRPC, dcerpc.ProtocolSequenceIPTCP},
+    "np":           {ProtocolSequenceNP, dcerpc.ProtocolSequenceNamedPipe},
+    "ncacn_np":     {ProtocolSequenceNP, dcerpc.ProtocolSequenceNamedPipe},
+  }
+)
+
+// BindingOptions controls which of the OXID bindings returned by the object exporter
+// are used to connect to the remote object, and in which order they are tried
+type BindingOptions struct {
+
+  // Protocols lists the accepted protocol sequences in order of preference ("tcp", "np"). Only the
+  // bindings of the first protocol offered by the object exporter are used. If empty, DefaultProtocols is used
+  Protocols []string
+
+  // PreferAddresses lists network addresses that are tried before any other binding of the same protocol
+  PreferAddresses []string
+
+  // SkipAddresses lists network addresses that are never used
+  SkipAddresses []string
+
+  // UseTargetAddress replaces the address of each binding with the target address.
+  // Otherwise, the binding address is tried first, and the target address is used as a fallback
+  UseTargetAddress bool
+}
+
+// protocolSequences returns the protocol sequences to request during activation
+func (b *BindingOptions) protocolSequences() (seqs []uint16, err error) {
+  protocols := b.Protocols
+
+  if len(protocols) == 0 {
+    protocols = DefaultProtocols
+  }
+  for _, p := range protocols {
+    proto, ok := bindingProtocols[strings.ToLower(p)]
+    if !ok {
+      return nil, fmt.Errorf("unsupported binding protocol %q", p)
+    }
+    seqs = append(seqs, proto.sequence)
+  }
+  return
+}
+
+// selectBindings filters and orders the OXID bindings of the first accepted protocol offered by the
+// object exporter. Each returned binding is complete, and bindings without a usable network address
+// use the target address
+func (b *BindingOptions) selectBindings(bindings []*dcom.StringBinding, target string) (selected []*dcerpc.StringBinding, err error) {
+
+  seqs, err := b.protocolSequences()
+  if err != nil {
+    return nil, err
+  }
+  seen := make(map[string]bool)
+
+  add := func(sb dcerpc.StringBinding) {
+    if s := sb.String(); !seen[s] {
+      seen[s] = true
+      selected = append(selected, &sb)
+    }
+  }
+
+  for _, seq := range seqs {
+    var preferred, other, fallback []dcerpc.StringBinding
+
+    for _, bind := range bindings {
+      if bind.TowerID != seq {
+        continue
+      }
+      sb, err := dcerpc.ParseStringBinding(bind.String())
+      if err != nil || sb.Endpoint == "" {
+        continue
+      }
+      addr := bindingAddress(sb)
+
+      if matchAddress(addr, b.SkipAddresses) {
+        continue
+      }
+      if b.UseTargetAddress || addr == "" {
+        sb.NetworkAddress, sb.ComputerName = target, ""
+        other = append(other, *sb)
+        continue
+      }
+      if matchAddress(addr, b.PreferAddresses) {
+        preferred = append(preferred, *sb)
+      } else {
+        other = append(other, *sb)
+      }
+      if sb.ProtocolSequence != dcerpc.ProtocolSequenceIPTCP {
+        continue // Named pipes are always opened on the target
+      }
+      // The binding address may not be reachable or resolvable from here
+      fb := *sb
+      fb.NetworkAddress, fb.ComputerName = target, ""
+      fallback = append(fallback, fb)
+    }
+    for _, group := range [][]dcerpc.StringBinding{preferred, other, fallback} {
+      for _, sb := range group {
+        add(sb)
+      }
+    }
+    if len(selected) > 0 {
+      // Bindings of a single protocol are used, so that the SMB dialer is only enabled for named pipes
+      return
+    }
+  }
+  return
+}
+
+// bindingAddress returns the network address or computer name of a string binding
+func bindingAddress(sb *dcerpc.StringBinding) string {
+  if sb.NetworkAddress != "" {
+    return sb.NetworkAddress
+  }
+  return strings.TrimLeft(sb.ComputerName, `\`)
+}
+
+func matchAddress(addr string, addrs []string) bool {
+  for _, a := range addrs {
+    if strings.EqualFold(addr, a) {
+      return true
+    }
+  }
+  return false
+}
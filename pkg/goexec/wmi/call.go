@@ -2,7 +2,6 @@ package wmiexec
 
 import (
   "context"
-  "encoding/json"
   "fmt"
   "github.com/rs/zerolog"
   "io"
@@ -13,7 +12,18 @@ type WmiCall struct {
 
   Class  string
   Method string
-  Args   map[string]any
+
+  // Args holds the method arguments. Each value is converted to the CIM type of the matching
+  // input parameter; embedded objects are maps with a __CLASS value (unless the parameter names the class),
+  // and datetimes may be RFC 3339 timestamps or durations
+  Args map[string]any
+
+  // Instance is the object path of an existing instance (i.e. Win32_Service.Name="Spooler").
+  // If set, Method is called on the instance rather than on Class
+  Instance string
+
+  // Get dumps the properties of Instance instead of calling a method
+  Get bool
 
   Out io.Writer
 }
@@ -21,18 +31,32 @@ type WmiCall struct {
 func (m *WmiCall) Call(ctx context.Context) (err error) {
   var outMap map[string]any
 
-  if outMap, err = m.query(ctx, m.Class, m.Method, m.Args); err != nil {
-    return
-  }
-  zerolog.Ctx(ctx).Info().Msg("WMI call successful")
+  log := zerolog.Ctx(ctx)
 
-  out, err := json.Marshal(outMap)
+  switch {
+  case m.Instance != "" && m.Get:
+    obj, err := m.getObject(ctx, m.Instance)
+    if err != nil {
+      return err
+    }
+    if obj.Instance == nil {
+      return fmt.Errorf("object path %q does not refer to an instance", m.Instance)
+    }
+    outMap = decodeValues(obj)
+    log.Info().Msg("WMI object retrieved")
 
-  if m.Out != nil {
-    // Write output with a trailing line feed
-    if _, err = m.Out.Write(append(out, 0x0a)); err != nil {
-      return fmt.Errorf("write output: %w", err)
+  default:
+    path := m.Instance
+    if path == "" {
+      path = m.Class // Static method call
     }
+    out, err := m.execMethodObject(ctx, path, m.Method, m.Args)
+    if err != nil {
+      return err
+    }
+    outMap = decodeValues(out)
+    log.Info().Msg("WMI call successful")
   }
-  return
+
+  return writeJsonLine(m.Out, outMap)
 }
@@ -0,0 +1,280 @@
+package wmiexec
+
+import (
+  "context"
+  "fmt"
+  "io"
+  "sort"
+  "strings"
+
+  "github.com/oiweiwei/go-msrpc/msrpc/dcom/wmio"
+  "github.com/rs/zerolog"
+)
+
+const (
+  MethodNamespaces = "Namespaces"
+  MethodClasses    = "Classes"
+
+  // RootNamespace is the top-level WMI namespace
+  RootNamespace = "//./root"
+)
+
+type WmiNamespaces struct {
+  Wmi
+
+  // NoRecurse only lists the direct children of the namespace
+  NoRecurse bool
+
+  // Format is the output format, either QueryFormatJson (JSON lines) or QueryFormatTable
+  Format string
+
+  Out io.Writer
+}
+
+type WmiClasses struct {
+  Wmi
+
+  // Filter selects classes by name. The wildcard "*" matches any sequence of characters
+  Filter string
+
+  // Methods includes the method signatures of each class
+  Methods bool
+
+  // Format is the output format, either QueryFormatJson (JSON lines) or QueryFormatTable
+  Format string
+
+  // PageSize is the number of classes fetched from the enumerator at once
+  PageSize uint32
+
+  Out io.Writer
+}
+
+type classInfo struct {
+  Class      string       `json:"class"`
+  Superclass string       `json:"superclass,omitempty"`
+  Methods    []methodInfo `json:"methods,omitempty"`
+}
+
+type methodInfo struct {
+  Name string      `json:"name"`
+  In   []paramInfo `json:"in"`
+  Out  []paramInfo `json:"out"`
+}
+
+type paramInfo struct {
+  Name string `json:"name"`
+  Type string `json:"type"`
+}
+
+func (m *WmiNamespaces) Call(ctx context.Context) (err error) {
+
+  log := zerolog.Ctx(ctx).With().
+    Str("module", ModuleName).
+    Str("method", MethodNamespaces).
+    Str("namespace", m.Resource).
+    Logger()
+
+  var rows []wmio.Values
+
+  err = m.walk(ctx, m.Resource, func(ns string) error {
+    if m.Format == QueryFormatTable {
+      rows = append(rows, wmio.Values{"Namespace": ns})
+      return nil
+    }
+    return writeJsonLine(m.Out, map[string]string{"namespace": ns})
+  })
+  if err != nil {
+    log.Error().Err(err).Msg("Failed to enumerate namespaces")
+    return
+  }
+
+  if m.Format == QueryFormatTable {
+    if err = writeTable(m.Out, rows, []string{"Namespace"}); err != nil {
+      return
+    }
+  }
+  log.Info().Msg("Namespace enumeration successful")
+  return
+}
+
+// walk enumerates the __NAMESPACE instances of ns, calling fn with the path of each child namespace.
+// Unless NoRecurse is set, each child namespace is opened and enumerated as well
+func (m *WmiNamespaces) walk(ctx context.Context, ns string, fn func(string) error) (err error) {
+
+  var children []string
+
+  err = m.execQuery(ctx, "SELECT Name FROM __NAMESPACE", DefaultPageSize, func(obj *wmio.Object) error {
+    if name, ok := obj.Values()["Name"].(string); ok {
+      children = append(children, strings.TrimSuffix(ns, "/")+"/"+name)
+    }
+    return nil
+  })
+  if err != nil {
+    return fmt.Errorf("enumerate namespace %q: %w", ns, err)
+  }
+  sort.Strings(children)
+
+  for _, child := range children {
+    if err = fn(child); err != nil {
+      return
+    }
+    if m.NoRecurse {
+      continue
+    }
+    if err = m.login(ctx, child); err != nil {
+      // Access to a namespace may be denied without affecting its siblings
+      zerolog.Ctx(ctx).Warn().Err(err).Str("namespace", child).Msg("Failed to open namespace")
+      continue
+    }
+    if err = m.walk(ctx, child, fn); err != nil {
+      return
+    }
+  }
+  return
+}
+
+func (m *WmiClasses) Call(ctx context.Context) (err error) {
+
+  log := zerolog.Ctx(ctx).With().
+    Str("module", ModuleName).
+    Str("method", MethodClasses).
+    Str("namespace", m.Resource).
+    Logger()
+
+  wql := "SELECT * FROM meta_class"
+
+  if m.Filter != "" {
+    wql += fmt.Sprintf(" WHERE __CLASS LIKE '%s'", likePattern(m.Filter))
+  }
+
+  var rows []wmio.Values
+
+  err = m.execQuery(ctx, wql, m.PageSize, func(obj *wmio.Object) error {
+    if obj.Class == nil {
+      return nil
+    }
+    info := classInfo{
+      Class:      obj.Class.CurrentClass.Name,
+      Superclass: obj.Class.ParentClass.Name,
+    }
+    if m.Methods {
+      info.Methods = classMethods(obj)
+    }
+
+    if m.Format == QueryFormatTable {
+      row := wmio.Values{"Class": info.Class, "Superclass": info.Superclass}
+
+      if m.Methods {
+        sigs := make([]string, len(info.Methods))
+        for i := range info.Methods {
+          sigs[i] = info.Methods[i].String()
+        }
+        row["Methods"] = strings.Join(sigs, "; ")
+      }
+      rows = append(rows, row)
+      return nil
+    }
+    return writeJsonLine(m.Out, info)
+  })
+  if err != nil {
+    log.Error().Err(err).Msg("Failed to enumerate classes")
+    return
+  }
+
+  if m.Format == QueryFormatTable {
+    columns := []string{"Class", "Superclass"}
+
+    if m.Methods {
+      columns = append(columns, "Methods")
+    }
+    sort.Slice(rows, func(i, j int) bool {
+      return rows[i]["Class"].(string) < rows[j]["Class"].(string)
+    })
+    if err = writeTable(m.Out, rows, columns); err != nil {
+      return
+    }
+  }
+  log.Info().Msg("Class enumeration successful")
+  return
+}
+
+// likePattern converts a class name filter to a WQL LIKE pattern. The LIKE wildcards are escaped so
+// that they match literally, and "*" is the only wildcard
+func likePattern(filter string) string {
+  return strings.NewReplacer(
+    `\`, `\\`,
+    "'", `\'`,
+    "[", "[[]",
+    "_", "[_]",
+    "%", "[%]",
+    "*", "%",
+  ).Replace(filter)
+}
+
+// String returns the method signature (i.e. "Create(string CommandLine) (uint32 ProcessId, uint32 ReturnValue)")
+func (mi methodInfo) String() string {
+  format := func(params []paramInfo) string {
+    out := make([]string, len(params))
+    for i, p := range params {
+      out[i] = p.Type + " " + p.Name
+    }
+    return "(" + strings.Join(out, ", ") + ")"
+  }
+  return mi.Name + format(mi.In) + " " + format(mi.Out)
+}
+
+// classMethods returns the methods of a class definition with their parameters
+func classMethods(obj *wmio.Object) (methods []methodInfo) {
+  for _, method := range obj.Class.CurrentClassMethods.Methods {
+    methods = append(methods, methodInfo{
+      Name: method.Name,
+      In:   signatureParams(&method.InputSignature),
+      Out:  signatureParams(&method.OutputSignature),
+    })
+  }
+  return
+}
+
+// signatureParams returns the parameters of a method signature object, ordered by their ID qualifier
+func signatureParams(sig *wmio.Object) []paramInfo {
+  if sig == nil || sig.Class == nil {
+    return []paramInfo{}
+  }
+  props := append([]*wmio.Property{}, sig.Class.CurrentClass.Properties...)
+
+  id := func(p *wmio.Property) int64 {
+    if v, ok := qualifierValue(p.Qualifiers, "ID"); ok {
+      if i, ok := v.(int32); ok {
+        return int64(i)
+      }
+    }
+    return 1<<31 + int64(p.Order) // ReturnValue has no ID
+  }
+  sort.SliceStable(props, func(i, j int) bool { return id(props[i]) < id(props[j]) })
+
+  params := make([]paramInfo, len(props))
+
+  for i, p := range props {
+    params[i] = paramInfo{Name: p.Name, Type: p.Value.Type.String()}
+
+    // The CIMTYPE qualifier includes the class of references and embedded objects (i.e. object:Win32_ProcessStartup)
+    if v, ok := qualifierValue(p.Qualifiers, "CIMTYPE"); ok {
+      if s, ok := v.(string); ok && s != "" {
+        params[i].Type = s
+        if p.Value.Type.IsArray() && !strings.HasPrefix(s, "[]") {
+          params[i].Type = "[]" + s
+        }
+      }
+    }
+  }
+  return params
+}
+
+func qualifierValue(qs []*wmio.Qualifier, name string) (any, bool) {
+  for _, q := range qs {
+    if strings.EqualFold(q.Name, name) {
+      return q.Value.Value, true
+    }
+  }
+  return nil, false
+}
@@ -0,0 +1,77 @@
+package wmiexec
+
+import (
+  "errors"
+  "fmt"
+  "strconv"
+  "strings"
+
+  "github.com/oiweiwei/go-msrpc/msrpc/dcom/wmi"
+  wmierr "github.com/oiweiwei/go-msrpc/msrpc/erref/wmi"
+)
+
+// wbemDescriptions describes the WBEM status codes commonly returned by remote operations
+var wbemDescriptions = map[wmi.Status]string{
+  wmi.StatusFailed:                      "the call failed",
+  wmi.StatusNotFound:                    "the object, class or property does not exist",
+  wmi.StatusAccessDenied:                "the current user is not allowed to perform the operation",
+  wmi.StatusProviderFailure:             "the provider failed to complete the operation",
+  wmi.StatusTypeMismatch:                "a value has the wrong type for the property or parameter",
+  wmi.StatusInvalidContext:              "the context object is invalid",
+  wmi.StatusInvalidParameter:            "a parameter of the call is invalid",
+  wmi.StatusNotAvailable:                "the resource is not available",
+  wmi.StatusNotSupported:                "the operation is not supported",
+  wmi.StatusProviderNotFound:            "no provider is registered for the class",
+  wmi.StatusProviderLoadFailure:         "the provider could not be loaded",
+  wmi.StatusTransportFailure:            "the connection to the remote WMI service failed",
+  wmi.StatusInvalidOperation:            "the operation is not valid for the object",
+  wmi.StatusAlreadyExists:               "the object already exists",
+  wmi.StatusInvalidNamespace:            "the namespace does not exist",
+  wmi.StatusInvalidObject:               "the object is invalid",
+  wmi.StatusInvalidClass:                "the class does not exist",
+  wmi.StatusInvalidQuery:                "the query is invalid",
+  wmi.StatusInvalidQueryType:            "the query language is not supported",
+  wmi.StatusIllegalNull:                 "a required value is null",
+  wmi.StatusInvalidCimType:              "the CIM type is invalid",
+  wmi.StatusInvalidMethod:               "the method does not exist",
+  wmi.StatusInvalidMethodParameters:     "the method parameters are invalid",
+  wmi.StatusInvalidProperty:             "the property does not exist",
+  wmi.StatusInvalidObjectPath:           "the object path is malformed",
+  wmi.StatusMethodNotImplemented:        "the method is not implemented by the provider",
+  wmi.StatusMethodDisabled:              "the method is disabled",
+  wmi.StatusUnparsableQuery:             "the query could not be parsed",
+  wmi.StatusNotEventClass:               "the class is not an event class",
+  wmi.StatusPrivilegeNotHeld:            "a required privilege is not held",
+  wmi.StatusEncryptedConnectionRequired: "the namespace requires an encrypted connection",
+  wmi.StatusProviderTimedOut:            "the provider timed out",
+  wmi.StatusProviderDisabled:            "the provider is disabled",
+  wmi.StatusServerTooBusy:               "the WMI service is too busy",
+  wmi.StatusQuotaViolation:              "a WMI quota was exceeded",
+}
+
+// wbemError adds a description of the WBEM status code carried by err, if any
+func wbemError(err error) error {
+  var we *wmierr.Error
+
+  if !errors.As(err, &we) {
+    return err
+  }
+  if desc, ok := wbemDescriptions[wmi.Status(we.Code)]; ok {
+    return fmt.Errorf("%w: %s", err, desc)
+  }
+  return err
+}
+
+// preferredLocale normalizes a locale for NTLMLogin. Both the MS_xxx form (i.e. MS_409) and
+// a hexadecimal LCID (i.e. 409 or 0x409) are accepted
+func preferredLocale(locale string) (string, error) {
+  if locale == "" {
+    return "", nil
+  }
+  lcid := strings.TrimPrefix(strings.TrimPrefix(strings.ToUpper(locale), "MS_"), "0X")
+
+  if _, err := strconv.ParseUint(lcid, 16, 16); err != nil {
+    return "", fmt.Errorf("invalid locale %q: expected MS_xxx with a hexadecimal LCID (i.e. MS_409)", locale)
+  }
+  return "MS_" + strings.ToLower(lcid), nil
+}
@@ -0,0 +1,165 @@
+package wmiexec
+
+import (
+  "context"
+  "errors"
+  "fmt"
+  "strings"
+  "time"
+
+  "github.com/FalconOpsLLC/goexec/pkg/goexec"
+  "github.com/oiweiwei/go-msrpc/msrpc/dcom/wmio"
+  "github.com/rs/zerolog"
+)
+
+const (
+  MethodEvent = "Event"
+
+  // EventNamespace is the namespace where permanent event subscriptions are registered
+  EventNamespace = "//./root/subscription"
+
+  ConsumerCommandLine  = "cmdline"
+  ConsumerActiveScript = "script"
+)
+
+type WmiEvent struct {
+  Wmi
+  goexec.Cleaner
+
+  IO goexec.ExecutionIO
+
+  // Name is the name of the event filter and consumer
+  Name string
+
+  // Trigger is the WQL event query of the filter. If empty, the filter fires once
+  // the UTC time of the target clock reaches StartDelay from now (to the second)
+  Trigger string
+
+  // TriggerNamespace is the namespace that Trigger is evaluated in
+  TriggerNamespace string
+
+  // Consumer selects the event consumer class, either ConsumerCommandLine or ConsumerActiveScript
+  Consumer string
+
+  // ScriptEngine is the scripting engine of the ActiveScriptEventConsumer (i.e. VBScript, JScript)
+  ScriptEngine string
+
+  // ScriptText is the script run by the ActiveScriptEventConsumer.
+  // If empty, a VBScript that runs the command line is generated
+  ScriptText string
+
+  NoDelete   bool
+  StartDelay time.Duration
+  StopDelay  time.Duration
+}
+
+func (m *WmiEvent) Execute(ctx context.Context, execIO *goexec.ExecutionIO) (err error) {
+
+  log := zerolog.Ctx(ctx).With().
+    Str("module", ModuleName).
+    Str("method", MethodEvent).
+    Str("name", m.Name).
+    Logger()
+  ctx = log.WithContext(ctx)
+
+  if execIO == nil {
+    return errors.New("execution IO is nil")
+  }
+
+  trigger := m.Trigger
+  if trigger == "" {
+    // The day, hour and minute are matched as well, so that the filter doesn't fire again a minute later.
+    // Win32_UTCTime is used since the time zone of the target is unknown
+    at := time.Now().UTC().Add(m.StartDelay)
+    trigger = fmt.Sprintf(
+      "SELECT * FROM __InstanceModificationEvent WITHIN 1 WHERE TargetInstance ISA 'Win32_UTCTime'"+
+        " AND TargetInstance.Day = %d AND TargetInstance.Hour = %d AND TargetInstance.Minute = %d AND TargetInstance.Second = %d",
+      at.Day(), at.Hour(), at.Minute(), at.Second())
+  }
+
+  consumerClass, consumerValues := "CommandLineEventConsumer", map[string]any{
+    "Name":                m.Name,
+    "CommandLineTemplate": execIO.String(),
+  }
+
+  if m.Consumer == ConsumerActiveScript {
+    script := m.ScriptText
+
+    if script == "" {
+      if !strings.EqualFold(m.ScriptEngine, "VBScript") {
+        return fmt.Errorf("script text is required for scripting engine %q", m.ScriptEngine)
+      }
+      script = fmt.Sprintf(`CreateObject("WScript.Shell").Run "%s", 0, False`, strings.ReplaceAll(execIO.String(), `"`, `""`))
+    }
+    consumerClass, consumerValues = "ActiveScriptEventConsumer", map[string]any{
+      "Name":            m.Name,
+      "ScriptingEngine": m.ScriptEngine,
+      "ScriptText":      script,
+    }
+  }
+
+  filterPath := objectPath("__EventFilter", "Name", m.Name)
+  consumerPath := objectPath(consumerClass, "Name", m.Name)
+  bindingPath := fmt.Sprintf(`__FilterToConsumerBinding.Consumer="%s",Filter="%s"`,
+    escapePathValue(consumerPath), escapePathValue(filterPath))
+
+  var created []string
+
+  deleteCreated := func(ctx context.Context) (err error) {
+    for i := len(created) - 1; i >= 0; i-- {
+      if e := m.deleteInstance(ctx, created[i]); e != nil {
+        log.Error().Err(e).Str("path", created[i]).Msg("Failed to delete event subscription object")
+        err = e
+        continue
+      }
+      log.Info().Str("path", created[i]).Msg("Event subscription object deleted")
+    }
+    return
+  }
+
+  for _, obj := range []struct {
+    class, path string
+    values      map[string]any
+  }{
+    {"__EventFilter", filterPath, map[string]any{
+      "Name":           m.Name,
+      "EventNamespace": m.TriggerNamespace,
+      "QueryLanguage":  "WQL",
+      "Query":          trigger,
+    }},
+    {consumerClass, consumerPath, consumerValues},
+    {"__FilterToConsumerBinding", bindingPath, map[string]any{
+      "Filter":   wmio.Value{Type: wmio.Ref, Value: filterPath},
+      "Consumer": wmio.Value{Type: wmio.Ref, Value: consumerPath},
+    }},
+  } {
+    if err = m.putInstance(ctx, obj.class, obj.values); err != nil {
+      log.Error().Err(err).Str("class", obj.class).Msg("Failed to create event subscription object")
+
+      if !m.NoDelete {
+        _ = deleteCreated(ctx)
+      }
+      return
+    }
+    log.Info().Str("path", obj.path).Msg("Event subscription object created")
+    created = append(created, obj.path)
+  }
+
+  log.Info().Str("trigger", trigger).Msg("Event subscription registered")
+
+  if !m.NoDelete {
+    m.AddCleaners(func(ctxInner context.Context) error {
+
+      log.Info().Msg("Waiting for event trigger...")
+
+      select {
+      case <-ctxInner.Done():
+        log.Warn().Msg("Event subscription deletion cancelled")
+        return nil
+      case <-time.After(m.StartDelay + m.StopDelay):
+      }
+      return deleteCreated(ctxInner)
+    })
+  }
+  return
+}
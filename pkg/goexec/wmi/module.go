@@ -9,11 +9,20 @@ import (
   "github.com/oiweiwei/go-msrpc/dcerpc"
   "github.com/oiweiwei/go-msrpc/msrpc/dcom"
   "github.com/oiweiwei/go-msrpc/msrpc/dcom/iactivation/v0"
+  "github.com/oiweiwei/go-msrpc/msrpc/dcom/iremunknown/v0"
+  "github.com/oiweiwei/go-msrpc/msrpc/dcom/iremunknown2/v0"
+  "github.com/oiweiwei/go-msrpc/msrpc/dcom/oaut"
   "github.com/oiweiwei/go-msrpc/msrpc/dcom/wmi"
+  "github.com/oiweiwei/go-msrpc/msrpc/dcom/wmi/ienumwbemclassobject/v0"
+  "github.com/oiweiwei/go-msrpc/msrpc/dcom/wmi/iwbemclassobject/v0"
   "github.com/oiweiwei/go-msrpc/msrpc/dcom/wmi/iwbemlevel1login/v0"
   "github.com/oiweiwei/go-msrpc/msrpc/dcom/wmi/iwbemservices/v0"
+  "github.com/oiweiwei/go-msrpc/msrpc/dcom/wmio"
   "github.com/oiweiwei/go-msrpc/msrpc/dcom/wmio/query"
+  "github.com/oiweiwei/go-msrpc/ndr"
   "github.com/rs/zerolog"
+  "strings"
+  "time"
 
   _ "github.com/oiweiwei/go-msrpc/msrpc/erref/ntstatus"
   _ "github.com/oiweiwei/go-msrpc/msrpc/erref/win32"
@@ -23,6 +32,9 @@ import (
 const (
   ModuleName      = "WMI"
   DefaultEndpoint = "ncacn_ip_tcp:[135]"
+
+  // DefaultPageSize is the number of objects requested with each call to IEnumWbemClassObject::Next
+  DefaultPageSize uint32 = 100
 )
 
 type Wmi struct {
@@ -31,7 +43,23 @@ type Wmi struct {
 
   Resource string
 
+  // Locale is the preferred locale passed to NTLMLogin (i.e. MS_409). If empty, the server default is used
+  Locale string
+
+  // LoginFlags are passed to NTLMLogin as-is
+  LoginFlags int32
+
+  // Binding selects the OXID bindings used to connect to the remote object
+  Binding BindingOptions
+
+  loginClient    iwbemlevel1login.Level1LoginClient
   servicesClient iwbemservices.ServicesClient
+
+  // oxid and remUnknown identify the object exporter of the activated object
+  oxid       uint64
+  remUnknown *dcom.IPID
+  // servicesRef is the reference to the IWbemServices interface of the open namespace, released when replaced
+  servicesRef *dcom.RemoteInterfaceReference
 }
 
 func (m *Wmi) Connect(ctx context.Context) (err error) {
@@ -57,11 +85,16 @@ func (m *Wmi) Init(ctx context.Context) (err error) {
     return fmt.Errorf("create IActivation client: %w", err)
   }
 
+  protocols, err := m.Binding.protocolSequences()
+  if err != nil {
+    return err
+  }
+
   actResponse, err := actClient.RemoteActivation(ctx, &iactivation.RemoteActivationRequest{
     ORPCThis:                   ORPCThis,
     ClassID:                    wmi.Level1LoginClassID.GUID(),
     IIDs:                       []*dcom.IID{iwbemlevel1login.Level1LoginIID},
-    RequestedProtocolSequences: []uint16{ProtocolSequenceRPC}, // FEATURE: Named pipe support?
+    RequestedProtocolSequences: protocols,
   })
   if err != nil {
     log.Error().Err(err).Msg("Failed to activate remote object")
@@ -73,19 +106,30 @@ func (m *Wmi) Init(ctx context.Context) (err error) {
 
   log.Info().Msg("Remote activation succeeded")
 
-  var newOpts []dcerpc.Option
+  m.oxid, m.remUnknown = actResponse.OXID, actResponse.RemoteUnknown
 
-  for _, bind := range actResponse.OXIDBindings.GetStringBindings() {
-    stringBinding, err := dcerpc.ParseStringBinding(bind.String())
-    if err != nil {
-      log.Debug().Err(err).Msg("Failed to parse string binding")
-      continue
+  offered := actResponse.OXIDBindings.GetStringBindings()
+
+  bindings, err := m.Binding.selectBindings(offered, m.Client.Target.AddressWithoutPort())
+  if err != nil {
+    return err
+  }
+  if len(bindings) == 0 {
+    for _, bind := range offered {
+      log.Debug().Str("binding", bind.String()).Msg("Object exporter offered binding")
     }
-    // Only consider ncacn_ip_tcp endpoints
-    if stringBinding.ProtocolSequence == dcerpc.ProtocolSequenceIPTCP {
-      stringBinding.NetworkAddress = m.Client.Target.AddressWithoutPort()
-      newOpts = append(newOpts, dcerpc.WithEndpoint(stringBinding.String()))
+    return fmt.Errorf("object exporter offered no usable bindings (%d offered)", len(offered))
+  }
+
+  var newOpts []dcerpc.Option
+
+  for _, bind := range bindings {
+    log.Debug().Str("binding", bind.String()).Msg("Using OXID binding")
+
+    if bind.ProtocolSequence == dcerpc.ProtocolSequenceNamedPipe {
+      m.Client.Smb = true // Named pipe bindings require the SMB dialer
     }
+    newOpts = append(newOpts, dcerpc.WithEndpoint(bind.String()))
   }
 
   if err = m.Client.Reconnect(ctx, newOpts...); err != nil {
@@ -96,26 +140,47 @@ func (m *Wmi) Init(ctx context.Context) (err error) {
   log.Info().Msg("Connected to remote instance")
 
   ipid := actResponse.InterfaceData[0].GetStandardObjectReference().Std.IPID
-  loginClient, err := iwbemlevel1login.NewLevel1LoginClient(ctx, m.Client.Dce(), dcom.WithIPID(ipid))
+  m.loginClient, err = iwbemlevel1login.NewLevel1LoginClient(ctx, m.Client.Dce(), dcom.WithIPID(ipid))
 
   if err != nil {
     log.Error().Err(err).Msg("Failed to create IWbemLevel1Login client")
     return fmt.Errorf("create IWbemLevel1Login client: %w", err)
   }
 
-  login, err := loginClient.NTLMLogin(ctx, &iwbemlevel1login.NTLMLoginRequest{
+  return m.login(ctx, m.Resource)
+}
+
+// login calls IWbemLevel1Login::NTLMLogin to open the provided namespace (i.e. //./root/cimv2),
+// then replaces the services client with one bound to the namespace
+func (m *Wmi) login(ctx context.Context, resource string) (err error) {
+
+  log := zerolog.Ctx(ctx).With().
+    Str("module", ModuleName).
+    Str("namespace", resource).Logger()
+
+  if m.loginClient == nil {
+    return errors.New("module has not been initialized")
+  }
+  locale, err := preferredLocale(m.Locale)
+  if err != nil {
+    return err
+  }
+
+  login, err := m.loginClient.NTLMLogin(ctx, &iwbemlevel1login.NTLMLoginRequest{
     This:            ORPCThis,
-    NetworkResource: m.Resource,
+    NetworkResource: resource,
+    PreferredLocale: locale,
+    Flags:           m.LoginFlags,
   })
 
   if err != nil {
     log.Error().Err(err).Msg("Failed to login on remote instance")
-    return fmt.Errorf("login: IWbemLevel1Login::NTLMLogin: %w", err)
+    return fmt.Errorf("login: IWbemLevel1Login::NTLMLogin: %w", wbemError(err))
   }
 
   log.Info().Msg("Completed NTLMLogin operation")
 
-  ipid = login.Namespace.InterfacePointer().IPID()
+  ipid := login.Namespace.InterfacePointer().IPID()
   m.servicesClient, err = iwbemservices.NewServicesClient(ctx, m.Client.Dce(), dcom.WithIPID(ipid))
 
   if err != nil {
@@ -125,6 +190,33 @@ func (m *Wmi) Init(ctx context.Context) (err error) {
 
   log.Info().Msg("Initialized services client")
 
+  // The services client of the previous namespace is no longer used
+  if err := m.release(ctx, m.servicesRef); err != nil {
+    log.Warn().Err(err).Msg("Failed to release previous services client")
+  }
+  m.servicesRef = nil
+
+  if std := login.Namespace.InterfacePointer().GetStandardObjectReference().Std; std != nil && std.PublicReferencesCount > 0 && std.OXID == m.oxid {
+    m.servicesRef = &dcom.RemoteInterfaceReference{IPID: std.IPID, PublicReferencesCount: std.PublicReferencesCount}
+  }
+  return
+}
+
+// release releases an interface reference with IRemUnknown2::RemRelease
+func (m *Wmi) release(ctx context.Context, ref *dcom.RemoteInterfaceReference) (err error) {
+  if ref == nil || m.remUnknown == nil {
+    return
+  }
+  ru, err := iremunknown2.NewRemoteUnknown2Client(ctx, m.Client.Dce(), dcom.WithIPID(m.remUnknown))
+  if err != nil {
+    return fmt.Errorf("init IRemUnknown2 client: %w", err)
+  }
+  if _, err = ru.RemoteUnknown().RemoteRelease(ctx, &iremunknown.RemoteReleaseRequest{
+    This:                ORPCThis,
+    InterfaceReferences: []*dcom.RemoteInterfaceReference{ref},
+  }); err != nil {
+    return fmt.Errorf("release interface: %w", err)
+  }
   return
 }
 
@@ -140,6 +232,304 @@ func (m *Wmi) query(ctx context.Context, class, method string, values map[string
     Object(); err == nil {
     return out.Values(), err
   } else {
-    return nil, fmt.Errorf("spawn WMI query: %w", err)
+    return nil, fmt.Errorf("spawn WMI query: %w", wbemError(err))
+  }
+}
+
+// execQuery calls IWbemServices::ExecQuery with the provided WQL query, then pages through
+// the returned enumerator, calling fn with each resulting object
+func (m *Wmi) execQuery(ctx context.Context, wql string, pageSize uint32, fn func(*wmio.Object) error) (err error) {
+  if m.servicesClient == nil {
+    return errors.New("module has not been initialized")
+  }
+  if pageSize == 0 {
+    pageSize = DefaultPageSize
+  }
+
+  queryResponse, err := m.servicesClient.ExecQuery(ctx, &iwbemservices.ExecQueryRequest{
+    This:          ORPCThis,
+    QueryLanguage: &oaut.String{Data: "WQL"},
+    Query:         &oaut.String{Data: wql},
+    Flags:         int32(wmi.GenericFlagTypeReturnImmediately | wmi.GenericFlagTypeForwardOnly),
+  })
+  if err != nil {
+    return fmt.Errorf("exec query: %w", wbemError(err))
+  }
+
+  enum, err := m.newObjectEnum(ctx, queryResponse.Enum)
+  if err != nil {
+    return err
+  }
+
+  for {
+    objs, done, err := enum.next(ctx, pageSize, -1)
+    if err != nil {
+      return fmt.Errorf("enumerate query results: %w", wbemError(err))
+    }
+    for _, obj := range objs {
+      if err = fn(obj); err != nil {
+        return err
+      }
+    }
+    if done || uint32(len(objs)) < pageSize {
+      return nil
+    }
+  }
+}
+
+// execNotificationQuery calls IWbemServices::ExecNotificationQuery with the provided WQL event query.
+// Events are fetched from the returned enumerator as they occur
+func (m *Wmi) execNotificationQuery(ctx context.Context, wql string) (*objectEnum, error) {
+  if m.servicesClient == nil {
+    return nil, errors.New("module has not been initialized")
+  }
+
+  queryResponse, err := m.servicesClient.ExecNotificationQuery(ctx, &iwbemservices.ExecNotificationQueryRequest{
+    This:          ORPCThis,
+    QueryLanguage: &oaut.String{Data: "WQL"},
+    Query:         &oaut.String{Data: wql},
+    Flags:         int32(wmi.GenericFlagTypeReturnImmediately | wmi.GenericFlagTypeForwardOnly),
+  })
+  if err != nil {
+    return nil, fmt.Errorf("exec notification query: %w", wbemError(err))
+  }
+  return m.newObjectEnum(ctx, queryResponse.Enum)
+}
+
+// objectEnum fetches objects from an IEnumWbemClassObject
+type objectEnum struct {
+  client ienumwbemclassobject.EnumClassObjectClient
+}
+
+func (m *Wmi) newObjectEnum(ctx context.Context, enum *wmi.EnumClassObject) (*objectEnum, error) {
+  if enum == nil {
+    return nil, errors.New("no enumerator returned")
+  }
+  client, err := ienumwbemclassobject.NewEnumClassObjectClient(ctx, m.Client.Dce(),
+    dcom.WithIPID(enum.InterfacePointer().IPID()))
+  if err != nil {
+    return nil, fmt.Errorf("create IEnumWbemClassObject client: %w", err)
+  }
+  return &objectEnum{client: client}, nil
+}
+
+// next calls IEnumWbemClassObject::Next to fetch up to count objects. A negative timeout waits indefinitely.
+// done is set when the enumeration has ended; a timeout returns no objects without an error
+func (e *objectEnum) next(ctx context.Context, count uint32, timeout time.Duration) (objs []*wmio.Object, done bool, err error) {
+
+  ms := int32(-1) // WBEM_INFINITE
+  if timeout >= 0 {
+    ms = int32(timeout.Milliseconds())
+  }
+
+  nextResponse, err := e.client.Next(ctx, &ienumwbemclassobject.NextRequest{
+    This:    ORPCThis,
+    Timeout: ms,
+    Count:   count,
+  })
+  if err != nil {
+    if nextResponse == nil {
+      return nil, false, err
+    }
+    switch wmi.Status(nextResponse.Return) {
+    case wmi.StatusFalse: // Returned with the final page
+      done = true
+    case wmi.StatusTimedout:
+    default:
+      return nil, false, err
+    }
+  }
+
+  for _, co := range nextResponse.Objects[:min(int(nextResponse.Returned), len(nextResponse.Objects))] {
+    obj, err := unmarshalObject(ctx, co)
+    if err != nil {
+      return nil, false, fmt.Errorf("decode object: %w", err)
+    }
+    objs = append(objs, obj)
+  }
+  return
+}
+
+// getObject calls IWbemServices::GetObject to fetch the class or instance at the provided object path
+func (m *Wmi) getObject(ctx context.Context, path string) (*wmio.Object, error) {
+  if m.servicesClient == nil {
+    return nil, errors.New("module has not been initialized")
+  }
+
+  getResponse, err := m.servicesClient.GetObject(ctx, &iwbemservices.GetObjectRequest{
+    This:       ORPCThis,
+    ObjectPath: &oaut.String{Data: path},
+    Object:     &wmi.ClassObject{},
+  })
+  if err != nil {
+    return nil, fmt.Errorf("get object %q: %w", path, wbemError(err))
+  }
+  return unmarshalObject(ctx, getResponse.Object)
+}
+
+// execMethod calls IWbemServices::ExecMethod on the provided object path. Unlike query, the path may
+// refer to an instance (i.e. Win32_Service.Name="Spooler"); the method signature is fetched from its class
+func (m *Wmi) execMethod(ctx context.Context, path, method string, values map[string]any) (map[string]any, error) {
+
+  out, err := m.execMethodObject(ctx, path, method, values)
+  if err != nil || out == nil {
+    return map[string]any{}, err
+  }
+  return out.Values(), nil
+}
+
+// execMethodObject works like execMethod, but returns the output parameters object.
+// The values are converted to the CIM types of the method's input parameters first
+func (m *Wmi) execMethodObject(ctx context.Context, path, method string, values map[string]any) (*wmio.Object, error) {
+
+  cls, err := m.getObject(ctx, classFromPath(path))
+  if err != nil {
+    return nil, err
+  }
+  in, _, err := cls.Method(method)
+  if err != nil {
+    return nil, fmt.Errorf("method %q: %w", method, err)
+  }
+
+  var inParams *wmi.ClassObject
+
+  // Methods without input parameters have an empty signature
+  if in.Class != nil {
+    coerced, err := m.coerceValues(ctx, in, values)
+    if err != nil {
+      return nil, fmt.Errorf("method %q: input parameters: %w", method, err)
+    }
+    params, err := in.New(coerced)
+    if err != nil {
+      return nil, fmt.Errorf("method %q: input parameters: %w", method, err)
+    }
+    if inParams, err = marshalObject(params); err != nil {
+      return nil, err
+    }
+  } else if len(values) > 0 {
+    return nil, fmt.Errorf("method %q has no input parameters", method)
+  }
+
+  execResponse, err := m.servicesClient.ExecMethod(ctx, &iwbemservices.ExecMethodRequest{
+    This:       ORPCThis,
+    ObjectPath: &oaut.String{Data: path},
+    MethodName: &oaut.String{Data: method},
+    InParams:   inParams,
+    OutParams:  &wmi.ClassObject{},
+  })
+  if err != nil {
+    return nil, fmt.Errorf("exec method %q: %w", method, wbemError(err))
+  }
+  if execResponse.OutParams == nil || len(execResponse.OutParams.Data) == 0 {
+    return nil, nil
+  }
+  return unmarshalObject(ctx, execResponse.OutParams)
+}
+
+// putInstance calls IWbemServices::PutInstance to create a new instance of class with the provided property values
+func (m *Wmi) putInstance(ctx context.Context, class string, values map[string]any) (err error) {
+
+  cls, err := m.getObject(ctx, class)
+  if err != nil {
+    return err
+  }
+  inst, err := cls.New(values)
+  if err != nil {
+    return fmt.Errorf("create %s instance: %w", class, err)
+  }
+  co, err := marshalObject(inst)
+  if err != nil {
+    return err
+  }
+
+  if _, err = m.servicesClient.PutInstance(ctx, &iwbemservices.PutInstanceRequest{
+    This:     ORPCThis,
+    Instance: co,
+    Flags:    int32(wmi.ChangeFlagTypeCreateOnly),
+  }); err != nil {
+    return fmt.Errorf("put %s instance: %w", class, wbemError(err))
+  }
+  return
+}
+
+// deleteInstance calls IWbemServices::DeleteInstance to remove the instance at the provided object path
+func (m *Wmi) deleteInstance(ctx context.Context, path string) (err error) {
+  if m.servicesClient == nil {
+    return errors.New("module has not been initialized")
+  }
+
+  if _, err = m.servicesClient.DeleteInstance(ctx, &iwbemservices.DeleteInstanceRequest{
+    This:       ORPCThis,
+    ObjectPath: &oaut.String{Data: path},
+  }); err != nil {
+    return fmt.Errorf("delete instance %q: %w", path, wbemError(err))
+  }
+  return
+}
+
+// objectPath builds the path of an instance with a single string key
+func objectPath(class, key, value string) string {
+  return fmt.Sprintf(`%s.%s="%s"`, class, key, escapePathValue(value))
+}
+
+// escapePathValue escapes a string key value for use in a WMI object path
+func escapePathValue(value string) string {
+  return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value)
+}
+
+// classFromPath extracts the class name from a WMI object path
+func classFromPath(path string) string {
+  if i := strings.IndexAny(path, "=@"); i >= 0 {
+    path = path[:i] // Remove key bindings
+  }
+  if i := strings.LastIndex(path, ":"); i >= 0 {
+    path = path[i+1:] // Remove namespace
+  }
+  if i := strings.Index(path, "."); i >= 0 {
+    path = path[:i]
+  }
+  return path
+}
+
+// marshalObject encodes a WMIO object as an IWbemClassObject reference
+func marshalObject(obj *wmio.Object) (*wmi.ClassObject, error) {
+
+  data, err := wmio.Marshal(obj)
+  if err != nil {
+    return nil, fmt.Errorf("marshal object: %w", err)
+  }
+
+  ref := &dcom.ObjectReference{
+    Signature: []byte(dcom.ObjectReferenceCustomSignature),
+    Flags:     dcom.ObjectReferenceTypeCustom,
+    IID:       iwbemclassobject.ClassObjectIID,
+    ObjectReference: &dcom.ObjectReference_ObjectReference{
+      Value: &dcom.ObjectReference_Custom{
+        Custom: &dcom.ObjectReferenceCustom{
+          ClassID:    wmi.ClassObjectUnmarshalClassID,
+          ObjectData: data,
+        },
+      },
+    },
+  }
+
+  if data, err = ndr.Marshal(ref, ndr.Opaque); err != nil {
+    return nil, fmt.Errorf("marshal object reference: %w", err)
+  }
+  return &wmi.ClassObject{Data: data}, nil
+}
+
+// unmarshalObject decodes an IWbemClassObject reference
+func unmarshalObject(ctx context.Context, co *wmi.ClassObject) (*wmio.Object, error) {
+  if co == nil {
+    return nil, errors.New("unmarshal object: object is nil")
+  }
+  obj, err := query.NewBuilder(ctx, nil, ComVersion).WithObject(co).Object()
+  if err != nil {
+    return nil, fmt.Errorf("unmarshal object: %w", err)
+  }
+  if obj == nil {
+    return nil, errors.New("unmarshal object: object is empty")
   }
+  return obj, nil
 }
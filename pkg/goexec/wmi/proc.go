@@ -3,18 +3,37 @@ package wmiexec
 import (
   "context"
   "errors"
+  "fmt"
+  "time"
+
   "github.com/FalconOpsLLC/goexec/pkg/goexec"
+  "github.com/oiweiwei/go-msrpc/msrpc/dcom/wmio"
   "github.com/rs/zerolog"
 )
 
 const (
   MethodProc = "Proc"
+
+  // DefaultPollInterval is the delay between process state checks with Wait
+  DefaultPollInterval = time.Second
 )
 
 type WmiProc struct {
   Wmi
   IO               goexec.ExecutionIO
   WorkingDirectory string
+
+  // Startup holds the optional Win32_ProcessStartup options
+  Startup ProcessStartup
+
+  // Wait blocks until the spawned process exits
+  Wait bool
+
+  // KillAfter terminates the process if it is still running after the provided duration. Implies Wait
+  KillAfter time.Duration
+
+  // PollInterval is the delay between process state checks
+  PollInterval time.Duration
 }
 
 func (m *WmiProc) Execute(ctx context.Context, execIO *goexec.ExecutionIO) (err error) {
@@ -29,19 +48,27 @@ func (m *WmiProc) Execute(ctx context.Context, execIO *goexec.ExecutionIO) (err
     return errors.New("execution IO is nil")
   }
 
-  out, err := m.query(ctx,
-    "Win32_Process",
-    "Create",
-    map[string]any{
-      "CommandLine": execIO.String(),
-      "WorkingDir":  m.WorkingDirectory,
-    },
-  )
+  args := map[string]any{
+    "CommandLine": execIO.String(),
+    "WorkingDir":  m.WorkingDirectory,
+  }
+
+  if startup, err := m.startupInfo(ctx, &m.Startup); err != nil {
+    log.Error().Err(err).Msg("Failed to create process startup information")
+    return fmt.Errorf("process startup information: %w", err)
+
+  } else if startup != nil {
+    args["ProcessStartupInformation"] = startup
+  }
+
+  out, err := m.query(ctx, "Win32_Process", "Create", args)
   if err != nil {
     return
   }
 
-  if pid, ok := out["ProcessId"].(uint32); pid != 0 {
+  pid, ok := out["ProcessId"].(uint32)
+
+  if pid != 0 {
     log = log.With().Uint32("pid", pid).Logger()
 
   } else if !ok {
@@ -54,6 +81,111 @@ func (m *WmiProc) Execute(ctx context.Context, execIO *goexec.ExecutionIO) (err
 
   } else if !ok {
     return errors.New("invalid call response")
+
+  } else if m.Wait || m.KillAfter > 0 {
+    return m.wait(log.WithContext(ctx), pid)
+  }
+  return
+}
+
+// wait blocks until the process exits, terminating it once KillAfter has elapsed.
+// The exit code is taken from Win32_ProcessStopTrace when the event subscription succeeds
+func (m *WmiProc) wait(ctx context.Context, pid uint32) (err error) {
+
+  log := zerolog.Ctx(ctx)
+
+  interval := m.PollInterval
+  if interval <= 0 {
+    interval = DefaultPollInterval
+  }
+
+  var deadline <-chan time.Time
+  if m.KillAfter > 0 {
+    deadline = time.After(m.KillAfter)
+  }
+
+  trace, err := m.execNotificationQuery(ctx, fmt.Sprintf("SELECT * FROM Win32_ProcessStopTrace WHERE ProcessID = %d", pid))
+  if err != nil {
+    log.Warn().Err(err).Msg("Failed to subscribe to process stop events, exit code will not be reported")
+  }
+
+  log.Info().Msg("Waiting for process to exit...")
+
+  for {
+    if trace != nil {
+      events, _, err := trace.next(ctx, 1, interval)
+      if err != nil {
+        log.Warn().Err(err).Msg("Failed to fetch process stop events")
+        trace = nil
+
+      } else if len(events) > 0 {
+        logExit(ctx, events[0])
+        return nil
+      }
+    } else {
+      select {
+      case <-ctx.Done():
+        return ctx.Err()
+      case <-time.After(interval):
+      }
+    }
+
+    running := false
+
+    if err = m.execQuery(ctx, fmt.Sprintf("SELECT ProcessId FROM Win32_Process WHERE ProcessId = %d", pid), 1, func(*wmio.Object) error {
+      running = true
+      return nil
+    }); err != nil {
+      log.Error().Err(err).Msg("Failed to query process state")
+      return fmt.Errorf("query process: %w", err)
+    }
+    if !running {
+      // The process may exit before the stop trace subscription is registered
+      log.Info().Msg("Process exited")
+      return nil
+    }
+
+    select {
+    case <-deadline:
+      deadline = nil
+
+      if err = m.terminate(ctx, pid); err != nil {
+        return err
+      }
+    default:
+    }
+  }
+}
+
+// terminate calls Win32_Process.Terminate on the process
+func (m *WmiProc) terminate(ctx context.Context, pid uint32) (err error) {
+
+  log := zerolog.Ctx(ctx)
+
+  out, err := m.execMethod(ctx, fmt.Sprintf(`Win32_Process.Handle="%d"`, pid), "Terminate", map[string]any{
+    "Reason": uint32(1),
+  })
+  if err != nil {
+    log.Error().Err(err).Msg("Failed to terminate process")
+    return fmt.Errorf("terminate process: %w", err)
+  }
+  if ret, ok := out["ReturnValue"].(uint32); ok && ret != 0 {
+    log.Error().Uint32("return", ret).Msg("Failed to terminate process")
+    return fmt.Errorf("terminate process returned non-zero exit code: %d", ret)
   }
+
+  log.Warn().Dur("after", m.KillAfter).Msg("Process terminated")
   return
 }
+
+// logExit logs the exit code reported by a Win32_ProcessStopTrace event
+func logExit(ctx context.Context, event *wmio.Object) {
+  e := zerolog.Ctx(ctx).Info()
+
+  if event.Instance != nil {
+    if code, ok := event.Values()["ExitStatus"].(uint32); ok {
+      e = e.Uint32("exit_code", code)
+    }
+  }
+  e.Msg("Process exited")
+}
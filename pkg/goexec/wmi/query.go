@@ -0,0 +1,161 @@
+package wmiexec
+
+import (
+  "context"
+  "encoding/json"
+  "fmt"
+  "io"
+  "sort"
+  "strings"
+  "text/tabwriter"
+
+  "github.com/oiweiwei/go-msrpc/msrpc/dcom/wmio"
+  "github.com/rs/zerolog"
+)
+
+const (
+  MethodQuery = "Query"
+
+  QueryFormatJson  = "json"
+  QueryFormatTable = "table"
+)
+
+type WmiQuery struct {
+  Wmi
+
+  // Query is the WQL query to execute (i.e. "SELECT * FROM Win32_Process")
+  Query string
+
+  // Properties selects the properties to output. If empty, every property is included
+  Properties []string
+
+  // Format is the output format, either QueryFormatJson (JSON lines) or QueryFormatTable
+  Format string
+
+  // PageSize is the number of objects fetched from the enumerator at once
+  PageSize uint32
+
+  Out io.Writer
+}
+
+func (m *WmiQuery) Call(ctx context.Context) (err error) {
+
+  log := zerolog.Ctx(ctx).With().
+    Str("module", ModuleName).
+    Str("method", MethodQuery).
+    Str("query", m.Query).
+    Logger()
+
+  var rows []wmio.Values
+
+  err = m.execQuery(ctx, m.Query, m.PageSize, func(obj *wmio.Object) error {
+    row := selectValues(objectValues(obj), m.Properties)
+
+    if m.Format == QueryFormatTable {
+      rows = append(rows, row) // The table is written once every column is known
+      return nil
+    }
+    return writeJsonLine(m.Out, row)
+  })
+  if err != nil {
+    log.Error().Err(err).Msg("Failed to execute WMI query")
+    return
+  }
+
+  if m.Format == QueryFormatTable {
+    if err = writeTable(m.Out, rows, m.Properties); err != nil {
+      return
+    }
+  }
+  log.Info().Msg("WMI query successful")
+  return
+}
+
+// objectValues returns the property values of an instance. Class objects (i.e. from "SELECT * FROM meta_class")
+// have no instance, so they're described by their __CLASS, __SUPERCLASS, __DERIVATION and __PROPERTY_COUNT
+func objectValues(obj *wmio.Object) wmio.Values {
+  if obj.Instance != nil {
+    return obj.Values()
+  }
+  values := make(wmio.Values)
+
+  if obj.Class != nil {
+    cls := obj.Class.CurrentClass
+    values["__CLASS"] = cls.Name
+    values["__SUPERCLASS"] = obj.Class.ParentClass.Name
+    values["__DERIVATION"] = cls.Derivation
+    values["__PROPERTY_COUNT"] = len(cls.Properties)
+  }
+  return values
+}
+
+// selectValues returns the properties of values with the provided names, matched case-insensitively
+func selectValues(values wmio.Values, names []string) wmio.Values {
+  if len(names) == 0 {
+    return values
+  }
+  out := make(wmio.Values, len(names))
+
+  for _, name := range names {
+    for k, v := range values {
+      if strings.EqualFold(k, name) {
+        out[name] = v
+        break
+      }
+    }
+  }
+  return out
+}
+
+// writeJsonLine writes v in JSON format with a trailing line feed
+func writeJsonLine(w io.Writer, v any) error {
+  if w == nil {
+    return nil
+  }
+  out, err := json.Marshal(v)
+  if err != nil {
+    return fmt.Errorf("marshal output: %w", err)
+  }
+  if _, err = w.Write(append(out, 0x0a)); err != nil {
+    return fmt.Errorf("write output: %w", err)
+  }
+  return nil
+}
+
+// writeTable writes rows as a table. If columns is empty, every property found in rows is included in alphabetical order
+func writeTable(w io.Writer, rows []wmio.Values, columns []string) error {
+  if w == nil {
+    return nil
+  }
+  if len(columns) == 0 {
+    seen := make(map[string]bool)
+
+    for _, row := range rows {
+      for k := range row {
+        if !seen[k] {
+          seen[k] = true
+          columns = append(columns, k)
+        }
+      }
+    }
+    sort.Strings(columns)
+  }
+
+  tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+  _, _ = fmt.Fprintln(tw, strings.Join(columns, "\t"))
+
+  for _, row := range rows {
+    cells := make([]string, len(columns))
+
+    for i, col := range columns {
+      if v, ok := row[col]; ok && v != nil {
+        cells[i] = strings.NewReplacer("\t", " ", "\n", " ", "\r", "").Replace(fmt.Sprint(v))
+      }
+    }
+    _, _ = fmt.Fprintln(tw, strings.Join(cells, "\t"))
+  }
+  if err := tw.Flush(); err != nil {
+    return fmt.Errorf("write output: %w", err)
+  }
+  return nil
+}
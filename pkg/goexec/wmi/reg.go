@@ -0,0 +1,356 @@
+package wmiexec
+
+import (
+  "context"
+  "encoding/hex"
+  "errors"
+  "fmt"
+  "io"
+  "strconv"
+  "strings"
+
+  "github.com/rs/zerolog"
+)
+
+const (
+  MethodReg = "Reg"
+
+  // RegNamespace is the namespace of the StdRegProv class
+  RegNamespace = "//./root/default"
+
+  RegActionGet    = "get"
+  RegActionSet    = "set"
+  RegActionDelete = "delete"
+  RegActionEnum   = "enum"
+)
+
+// Registry value types, as returned by StdRegProv.EnumValues
+const (
+  RegSz       int32 = 1
+  RegExpandSz int32 = 2
+  RegBinary   int32 = 3
+  RegDword    int32 = 4
+  RegMultiSz  int32 = 7
+  RegQword    int32 = 11
+)
+
+var (
+  regHives = map[string]uint32{
+    "HKCR":                0x80000000,
+    "HKEY_CLASSES_ROOT":   0x80000000,
+    "HKCU":                0x80000001,
+    "HKEY_CURRENT_USER":   0x80000001,
+    "HKLM":                0x80000002,
+    "HKEY_LOCAL_MACHINE":  0x80000002,
+    "HKU":                 0x80000003,
+    "HKEY_USERS":          0x80000003,
+    "HKCC":                0x80000005,
+    "HKEY_CURRENT_CONFIG": 0x80000005,
+  }
+
+  regTypes = map[string]int32{
+    "SZ":        RegSz,
+    "EXPAND_SZ": RegExpandSz,
+    "BINARY":    RegBinary,
+    "DWORD":     RegDword,
+    "MULTI_SZ":  RegMultiSz,
+    "QWORD":     RegQword,
+  }
+
+  // regMethods maps each value type to the StdRegProv get and set methods, and the name of the value parameter
+  regMethods = map[int32]struct{ get, set, param string }{
+    RegSz:       {"GetStringValue", "SetStringValue", "sValue"},
+    RegExpandSz: {"GetExpandedStringValue", "SetExpandedStringValue", "sValue"},
+    RegBinary:   {"GetBinaryValue", "SetBinaryValue", "uValue"},
+    RegDword:    {"GetDWORDValue", "SetDWORDValue", "uValue"},
+    RegMultiSz:  {"GetMultiStringValue", "SetMultiStringValue", "sValue"},
+    RegQword:    {"GetQWORDValue", "SetQWORDValue", "uValue"},
+  }
+)
+
+type WmiReg struct {
+  Wmi
+
+  // Action is one of RegActionGet, RegActionSet, RegActionDelete or RegActionEnum
+  Action string
+
+  // Key is the registry key path, including the hive (i.e. HKLM\SOFTWARE\Microsoft)
+  Key string
+
+  // Name is the name of the registry value. If empty, the default value is used
+  Name string
+
+  // Type is the registry value type (i.e. SZ, DWORD). If empty, get determines the type with EnumValues
+  Type string
+
+  // Data holds the value to set. MULTI_SZ values use each element, BINARY values are hex encoded
+  Data []string
+
+  Out io.Writer
+}
+
+type regValue struct {
+  Key   string `json:"key"`
+  Name  string `json:"name"`
+  Type  string `json:"type"`
+  Value any    `json:"value,omitempty"`
+}
+
+func (m *WmiReg) Call(ctx context.Context) (err error) {
+
+  log := zerolog.Ctx(ctx).With().
+    Str("module", ModuleName).
+    Str("method", MethodReg).
+    Str("action", m.Action).
+    Str("key", m.Key).
+    Logger()
+  ctx = log.WithContext(ctx)
+
+  hive, subKey, err := parseRegKey(m.Key)
+  if err != nil {
+    return err
+  }
+
+  switch m.Action {
+  case RegActionGet:
+    err = m.get(ctx, hive, subKey)
+  case RegActionSet:
+    err = m.set(ctx, hive, subKey)
+  case RegActionDelete:
+    err = m.delete(ctx, hive, subKey)
+  case RegActionEnum:
+    err = m.enum(ctx, hive, subKey)
+  default:
+    return fmt.Errorf("unknown registry action %q", m.Action)
+  }
+  if err != nil {
+    log.Error().Err(err).Msg("Registry operation failed")
+    return
+  }
+  log.Info().Msg("Registry operation successful")
+  return
+}
+
+func (m *WmiReg) get(ctx context.Context, hive uint32, subKey string) (err error) {
+
+  typ, err := m.valueType(ctx, hive, subKey)
+  if err != nil {
+    return err
+  }
+
+  out, err := m.regCall(ctx, regMethods[typ].get, map[string]any{
+    "hDefKey":     hive,
+    "sSubKeyName": subKey,
+    "sValueName":  m.Name,
+  })
+  if err != nil {
+    return err
+  }
+
+  value := out[regMethods[typ].param]
+
+  if b, ok := value.([]uint8); ok {
+    value = hex.EncodeToString(b)
+  }
+  return writeJsonLine(m.Out, regValue{
+    Key:   m.Key,
+    Name:  m.Name,
+    Type:  regTypeName(typ),
+    Value: value,
+  })
+}
+
+func (m *WmiReg) set(ctx context.Context, hive uint32, subKey string) (err error) {
+
+  typ, err := parseRegType(m.Type)
+  if err != nil {
+    return err
+  }
+  value, err := parseRegData(typ, m.Data)
+  if err != nil {
+    return err
+  }
+
+  // CreateKey succeeds if the key already exists
+  if _, err = m.regCall(ctx, "CreateKey", map[string]any{
+    "hDefKey":     hive,
+    "sSubKeyName": subKey,
+  }); err != nil {
+    return err
+  }
+
+  _, err = m.regCall(ctx, regMethods[typ].set, map[string]any{
+    "hDefKey":              hive,
+    "sSubKeyName":          subKey,
+    "sValueName":           m.Name,
+    regMethods[typ].param: value,
+  })
+  return
+}
+
+func (m *WmiReg) delete(ctx context.Context, hive uint32, subKey string) (err error) {
+
+  // Without a value name, the key itself is deleted
+  if m.Name == "" {
+    _, err = m.regCall(ctx, "DeleteKey", map[string]any{
+      "hDefKey":     hive,
+      "sSubKeyName": subKey,
+    })
+    return
+  }
+  _, err = m.regCall(ctx, "DeleteValue", map[string]any{
+    "hDefKey":     hive,
+    "sSubKeyName": subKey,
+    "sValueName":  m.Name,
+  })
+  return
+}
+
+func (m *WmiReg) enum(ctx context.Context, hive uint32, subKey string) (err error) {
+
+  keys, err := m.regCall(ctx, "EnumKey", map[string]any{
+    "hDefKey":     hive,
+    "sSubKeyName": subKey,
+  })
+  if err != nil {
+    return err
+  }
+  names, types, err := m.enumValues(ctx, hive, subKey)
+  if err != nil {
+    return err
+  }
+
+  values := make([]regValue, len(names))
+
+  for i := range names {
+    values[i] = regValue{Key: m.Key, Name: names[i]}
+
+    if i < len(types) {
+      values[i].Type = regTypeName(types[i])
+    }
+  }
+  subKeys, _ := keys["sNames"].([]string)
+
+  return writeJsonLine(m.Out, map[string]any{
+    "key":     m.Key,
+    "subkeys": subKeys,
+    "values":  values,
+  })
+}
+
+// valueType returns the type of the value selected by m.Type, or looks up the type with EnumValues
+func (m *WmiReg) valueType(ctx context.Context, hive uint32, subKey string) (int32, error) {
+
+  if m.Type != "" {
+    return parseRegType(m.Type)
+  }
+
+  names, types, err := m.enumValues(ctx, hive, subKey)
+  if err != nil {
+    return 0, err
+  }
+  for i := range names {
+    if strings.EqualFold(names[i], m.Name) && i < len(types) {
+      if _, ok := regMethods[types[i]]; !ok {
+        return 0, fmt.Errorf("unsupported registry value type %d", types[i])
+      }
+      return types[i], nil
+    }
+  }
+  if m.Name == "" {
+    return RegSz, nil // The default value isn't always enumerated
+  }
+  return 0, fmt.Errorf("registry value %q not found", m.Name)
+}
+
+func (m *WmiReg) enumValues(ctx context.Context, hive uint32, subKey string) (names []string, types []int32, err error) {
+
+  out, err := m.regCall(ctx, "EnumValues", map[string]any{
+    "hDefKey":     hive,
+    "sSubKeyName": subKey,
+  })
+  if err != nil {
+    return nil, nil, err
+  }
+  names, _ = out["sNames"].([]string)
+  types, _ = out["Types"].([]int32)
+  return
+}
+
+// regCall calls a StdRegProv method and checks the returned value
+func (m *WmiReg) regCall(ctx context.Context, method string, values map[string]any) (map[string]any, error) {
+
+  out, err := m.query(ctx, "StdRegProv", method, values)
+  if err != nil {
+    return nil, err
+  }
+  if ret, ok := out["ReturnValue"].(uint32); !ok {
+    return nil, errors.New("invalid call response")
+
+  } else if ret != 0 {
+    return nil, fmt.Errorf("StdRegProv.%s returned non-zero exit code: 0x%08x", method, ret)
+  }
+  return out, nil
+}
+
+// parseRegKey splits a registry key path into the hive and subkey
+func parseRegKey(key string) (hive uint32, subKey string, err error) {
+  root, subKey, _ := strings.Cut(strings.Trim(key, `\`), `\`)
+
+  if hive, ok := regHives[strings.ToUpper(root)]; ok {
+    return hive, subKey, nil
+  }
+  return 0, "", fmt.Errorf("unknown registry hive %q", root)
+}
+
+// parseRegType parses a registry value type name, with or without the REG_ prefix
+func parseRegType(name string) (int32, error) {
+  if typ, ok := regTypes[strings.TrimPrefix(strings.ToUpper(name), "REG_")]; ok {
+    return typ, nil
+  }
+  return 0, fmt.Errorf("unknown registry value type %q", name)
+}
+
+// parseRegData converts the provided data to the Go type expected by the StdRegProv set method of typ
+func parseRegData(typ int32, data []string) (any, error) {
+
+  if typ == RegMultiSz {
+    return data, nil
+  }
+  if len(data) != 1 {
+    return nil, fmt.Errorf("expected exactly one data value, got %d", len(data))
+  }
+
+  switch typ {
+  case RegDword:
+    v, err := strconv.ParseUint(data[0], 0, 32)
+    if err != nil {
+      return nil, fmt.Errorf("parse DWORD value: %w", err)
+    }
+    return uint32(v), nil
+
+  case RegQword:
+    v, err := strconv.ParseUint(data[0], 0, 64)
+    if err != nil {
+      return nil, fmt.Errorf("parse QWORD value: %w", err)
+    }
+    return v, nil
+
+  case RegBinary:
+    v, err := hex.DecodeString(strings.TrimPrefix(data[0], "0x"))
+    if err != nil {
+      return nil, fmt.Errorf("parse BINARY value: %w", err)
+    }
+    return v, nil
+  }
+  return data[0], nil
+}
+
+func regTypeName(typ int32) string {
+  for name, t := range regTypes {
+    if t == typ {
+      return "REG_" + name
+    }
+  }
+  return strconv.Itoa(int(typ))
+}
@@ -0,0 +1,143 @@
+package wmiexec
+
+import (
+  "context"
+  "fmt"
+  "strconv"
+  "strings"
+
+  "github.com/oiweiwei/go-msrpc/msrpc/dcom/wmio"
+)
+
+var (
+  // showWindowValues maps the ShowWindow names accepted by Win32_ProcessStartup to their values
+  showWindowValues = map[string]uint32{
+    "SW_HIDE":            0,
+    "SW_SHOWNORMAL":      1,
+    "SW_SHOWMINIMIZED":   2,
+    "SW_SHOWMAXIMIZED":   3,
+    "SW_SHOWNOACTIVATE":  4,
+    "SW_SHOW":            5,
+    "SW_MINIMIZE":        6,
+    "SW_SHOWMINNOACTIVE": 7,
+    "SW_SHOWNA":          8,
+    "SW_RESTORE":         9,
+    "SW_SHOWDEFAULT":     10,
+    "SW_FORCEMINIMIZE":   11,
+  }
+
+  createFlagValues = map[string]uint32{
+    "DEBUG_PROCESS":              0x1,
+    "DEBUG_ONLY_THIS_PROCESS":    0x2,
+    "CREATE_SUSPENDED":           0x4,
+    "DETACHED_PROCESS":           0x8,
+    "CREATE_NEW_CONSOLE":         0x10,
+    "CREATE_NEW_PROCESS_GROUP":   0x200,
+    "CREATE_UNICODE_ENVIRONMENT": 0x400,
+    "CREATE_BREAKAWAY_FROM_JOB":  0x1000000,
+    "CREATE_DEFAULT_ERROR_MODE":  0x4000000,
+  }
+
+  priorityClassValues = map[string]uint32{
+    "NORMAL":       0x20,
+    "IDLE":         0x40,
+    "HIGH":         0x80,
+    "REALTIME":     0x100,
+    "BELOW_NORMAL": 0x4000,
+    "ABOVE_NORMAL": 0x8000,
+  }
+)
+
+// ProcessStartup holds the Win32_ProcessStartup options passed to Win32_Process.Create.
+// Named values are case-insensitive, and numeric values are accepted as well
+type ProcessStartup struct {
+  // ShowWindow is the window state of the process (i.e. SW_HIDE, SW_SHOWNORMAL)
+  ShowWindow string
+
+  // CreateFlags are combined to form the process creation flags (i.e. CREATE_SUSPENDED, CREATE_NEW_CONSOLE)
+  CreateFlags []string
+
+  // PriorityClass is the scheduling priority of the process (i.e. IDLE, BELOW_NORMAL, HIGH)
+  PriorityClass string
+
+  // EnvironmentVariables are set for the process in KEY=VALUE format
+  EnvironmentVariables []string
+}
+
+// values returns the Win32_ProcessStartup properties that were set
+func (s *ProcessStartup) values() (values map[string]any, err error) {
+
+  values = make(map[string]any)
+
+  if s.ShowWindow != "" {
+    v, err := parseNamedValue(s.ShowWindow, showWindowValues, "SW_", 16)
+    if err != nil {
+      return nil, fmt.Errorf("parse ShowWindow: %w", err)
+    }
+    values["ShowWindow"] = uint16(v)
+  }
+  if len(s.CreateFlags) > 0 {
+    var flags uint32
+
+    for _, f := range s.CreateFlags {
+      v, err := parseNamedValue(f, createFlagValues, "", 32)
+      if err != nil {
+        return nil, fmt.Errorf("parse CreateFlags: %w", err)
+      }
+      flags |= uint32(v)
+    }
+    values["CreateFlags"] = flags
+  }
+  if s.PriorityClass != "" {
+    v, err := parseNamedValue(strings.TrimSuffix(strings.ToUpper(s.PriorityClass), "_PRIORITY_CLASS"), priorityClassValues, "", 32)
+    if err != nil {
+      return nil, fmt.Errorf("parse PriorityClass: %w", err)
+    }
+    values["PriorityClass"] = uint32(v)
+  }
+  if len(s.EnvironmentVariables) > 0 {
+    for _, env := range s.EnvironmentVariables {
+      if k, _, ok := strings.Cut(env, "="); !ok || k == "" {
+        return nil, fmt.Errorf("invalid environment variable %q: expected KEY=VALUE", env)
+      }
+    }
+    values["EnvironmentVariables"] = s.EnvironmentVariables
+  }
+  return
+}
+
+// startupInfo creates the Win32_ProcessStartup instance for Win32_Process.Create,
+// or returns nil if no startup options were set
+func (m *Wmi) startupInfo(ctx context.Context, s *ProcessStartup) (*wmio.Object, error) {
+
+  values, err := s.values()
+  if err != nil || len(values) == 0 {
+    return nil, err
+  }
+
+  cls, err := m.getObject(ctx, "Win32_ProcessStartup")
+  if err != nil {
+    return nil, err
+  }
+  inst, err := cls.New(values)
+  if err != nil {
+    return nil, fmt.Errorf("create Win32_ProcessStartup instance: %w", err)
+  }
+  return inst, nil
+}
+
+// parseNamedValue parses a numeric value or one of the provided names, with or without prefix
+func parseNamedValue(s string, names map[string]uint32, prefix string, bits int) (uint64, error) {
+  if v, err := strconv.ParseUint(s, 0, bits); err == nil {
+    return v, nil
+  }
+  name := strings.ToUpper(s)
+
+  if v, ok := names[name]; ok {
+    return uint64(v), nil
+  }
+  if v, ok := names[prefix+name]; ok && prefix != "" {
+    return uint64(v), nil
+  }
+  return 0, fmt.Errorf("unknown value %q", s)
+}
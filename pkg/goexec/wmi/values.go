@@ -0,0 +1,455 @@
+package wmiexec
+
+import (
+  "context"
+  "encoding/json"
+  "fmt"
+  "math"
+  "strconv"
+  "strings"
+  "time"
+
+  "github.com/oiweiwei/go-msrpc/msrpc/dcom/wmio"
+)
+
+const (
+  // cimDateTimeLayout is the layout of a CIM datetime without the UTC offset (yyyymmddHHMMSS.mmmmmm)
+  cimDateTimeLayout = "20060102150405.000000"
+)
+
+// coerceValues converts values to the CIM types of the properties of cls (i.e. a method input signature).
+// Property names are matched case-insensitively, and unknown names are rejected
+func (m *Wmi) coerceValues(ctx context.Context, cls *wmio.Object, values map[string]any) (map[string]any, error) {
+
+  out := make(map[string]any, len(values))
+
+  if len(values) == 0 {
+    return out, nil
+  }
+  if cls == nil || cls.Class == nil {
+    return nil, fmt.Errorf("unexpected values for an empty signature")
+  }
+
+  for name, value := range values {
+    var prop *wmio.Property
+
+    for _, p := range cls.Class.CurrentClass.Properties {
+      if strings.EqualFold(p.Name, name) {
+        prop = p
+        break
+      }
+    }
+    if prop == nil {
+      return nil, fmt.Errorf("%s has no property %q", cls.Class.CurrentClass.Name, name)
+    }
+    if value == nil {
+      out[prop.Name] = nil
+      continue
+    }
+    if v, ok := value.(wmio.Value); ok {
+      out[prop.Name] = v // Already typed
+      continue
+    }
+    cimType, _ := qualifierValue(prop.Qualifiers, "CIMTYPE")
+    cimTypeName, _ := cimType.(string)
+
+    v, err := m.coerceValue(ctx, prop.Value.Type, cimTypeName, value)
+    if err != nil {
+      return nil, fmt.Errorf("%s: %w", prop.Name, err)
+    }
+    out[prop.Name] = wmio.Value{Type: prop.Value.Type, Value: v}
+  }
+  return out, nil
+}
+
+// coerceValue converts a value decoded from JSON (or a native Go value) to the Go type of typ.
+// cimType is the CIMTYPE qualifier of the property, which names the class of embedded objects
+func (m *Wmi) coerceValue(ctx context.Context, typ wmio.CIMType, cimType string, value any) (any, error) {
+
+  if typ.IsArray() {
+    base := typ &^ wmio.CIMArray
+
+    var items []any
+
+    switch v := value.(type) {
+    case []any:
+      items = v
+    case []string:
+      for _, s := range v {
+        items = append(items, s)
+      }
+    default:
+      items = []any{value} // A single value is accepted as a one-element array
+    }
+
+    conv := make([]any, len(items))
+
+    for i := range items {
+      c, err := m.coerceValue(ctx, base, cimType, items[i])
+      if err != nil {
+        return nil, fmt.Errorf("element %d: %w", i, err)
+      }
+      conv[i] = c
+    }
+    return typedSlice(base, conv), nil
+  }
+
+  switch typ {
+  case wmio.Int8, wmio.Int16, wmio.Int32, wmio.Int64:
+    bits := map[wmio.CIMType]int{wmio.Int8: 8, wmio.Int16: 16, wmio.Int32: 32, wmio.Int64: 64}[typ]
+
+    s, err := numberString(value)
+    if err != nil {
+      return nil, err
+    }
+    i, err := strconv.ParseInt(s, 0, bits)
+    if err != nil {
+      return nil, fmt.Errorf("parse %s: %w", typ, err)
+    }
+    switch typ {
+    case wmio.Int8:
+      return int8(i), nil
+    case wmio.Int16:
+      return int16(i), nil
+    case wmio.Int32:
+      return int32(i), nil
+    }
+    return i, nil
+
+  case wmio.Uint8, wmio.Uint16, wmio.Uint32, wmio.Uint64:
+    bits := map[wmio.CIMType]int{wmio.Uint8: 8, wmio.Uint16: 16, wmio.Uint32: 32, wmio.Uint64: 64}[typ]
+
+    s, err := numberString(value)
+    if err != nil {
+      return nil, err
+    }
+    u, err := strconv.ParseUint(s, 0, bits)
+    if err != nil {
+      return nil, fmt.Errorf("parse %s: %w", typ, err)
+    }
+    switch typ {
+    case wmio.Uint8:
+      return uint8(u), nil
+    case wmio.Uint16:
+      return uint16(u), nil
+    case wmio.Uint32:
+      return uint32(u), nil
+    }
+    return u, nil
+
+  case wmio.Float32, wmio.Float64:
+    s, err := numberString(value)
+    if err != nil {
+      return nil, err
+    }
+    bits := 64
+    if typ == wmio.Float32 {
+      bits = 32
+    }
+    f, err := strconv.ParseFloat(s, bits)
+    if err != nil {
+      return nil, fmt.Errorf("parse %s: %w", typ, err)
+    }
+    if typ == wmio.Float32 {
+      return float32(f), nil
+    }
+    return f, nil
+
+  case wmio.Bool:
+    switch v := value.(type) {
+    case bool:
+      return v, nil
+    case string:
+      b, err := strconv.ParseBool(v)
+      if err != nil {
+        return nil, fmt.Errorf("parse bool: %w", err)
+      }
+      return b, nil
+    }
+
+  case wmio.String, wmio.Ref:
+    switch v := value.(type) {
+    case string:
+      return v, nil
+    case json.Number:
+      return v.String(), nil
+    }
+
+  case wmio.Rune:
+    if s, ok := value.(string); ok {
+      if r := []rune(s); len(r) == 1 && r[0] <= math.MaxUint16 {
+        return uint16(r[0]), nil
+      }
+      return nil, fmt.Errorf("expected a single character, got %q", s)
+    }
+
+  case wmio.DateTime:
+    switch v := value.(type) {
+    case string:
+      return toCIMDateTime(v)
+    case time.Time:
+      return formatCIMDateTime(v), nil
+    }
+
+  case wmio.CIMObject:
+    switch v := value.(type) {
+    case *wmio.Object:
+      return v, nil
+    case map[string]any:
+      return m.embeddedObject(ctx, cimType, v)
+    }
+  }
+  return nil, fmt.Errorf("cannot convert %T to %s", value, typ)
+}
+
+// embeddedObject creates an instance of the class named by the __CLASS key of values,
+// or by the CIMTYPE qualifier of the property (i.e. object:Win32_ProcessStartup)
+func (m *Wmi) embeddedObject(ctx context.Context, cimType string, values map[string]any) (*wmio.Object, error) {
+
+  class := strings.TrimPrefix(cimType, "object:")
+  props := make(map[string]any, len(values))
+
+  for k, v := range values {
+    if strings.EqualFold(k, "__CLASS") {
+      class, _ = v.(string)
+      continue
+    }
+    props[k] = v
+  }
+  if class == "" || class == "object" {
+    return nil, fmt.Errorf("embedded object requires a __CLASS value")
+  }
+
+  cls, err := m.getObject(ctx, class)
+  if err != nil {
+    return nil, err
+  }
+  coerced, err := m.coerceValues(ctx, cls, props)
+  if err != nil {
+    return nil, err
+  }
+  inst, err := cls.New(coerced)
+  if err != nil {
+    return nil, fmt.Errorf("create %s instance: %w", class, err)
+  }
+  return inst, nil
+}
+
+// numberString returns the string form of a numeric value for parsing
+func numberString(value any) (string, error) {
+  switch v := value.(type) {
+  case json.Number:
+    return v.String(), nil
+  case string:
+    return strings.TrimSpace(v), nil
+  case float64:
+    if v != math.Trunc(v) || math.Abs(v) > 1<<53 {
+      return strconv.FormatFloat(v, 'g', -1, 64), nil
+    }
+    return strconv.FormatFloat(v, 'f', -1, 64), nil
+  case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32:
+    return fmt.Sprint(v), nil
+  }
+  return "", fmt.Errorf("expected a number, got %T", value)
+}
+
+// typedSlice converts the coerced elements of an array to the slice type expected for base
+func typedSlice(base wmio.CIMType, items []any) any {
+  switch base {
+  case wmio.Int8:
+    return sliceOf[int8](items)
+  case wmio.Uint8:
+    return sliceOf[uint8](items)
+  case wmio.Int16:
+    return sliceOf[int16](items)
+  case wmio.Uint16, wmio.Rune:
+    return sliceOf[uint16](items)
+  case wmio.Int32:
+    return sliceOf[int32](items)
+  case wmio.Uint32:
+    return sliceOf[uint32](items)
+  case wmio.Int64:
+    return sliceOf[int64](items)
+  case wmio.Uint64:
+    return sliceOf[uint64](items)
+  case wmio.Float32:
+    return sliceOf[float32](items)
+  case wmio.Float64:
+    return sliceOf[float64](items)
+  case wmio.Bool:
+    return sliceOf[bool](items)
+  case wmio.CIMObject:
+    return sliceOf[*wmio.Object](items)
+  }
+  return sliceOf[string](items)
+}
+
+func sliceOf[T any](items []any) []T {
+  out := make([]T, len(items))
+  for i := range items {
+    out[i], _ = items[i].(T)
+  }
+  return out
+}
+
+// toCIMDateTime converts an RFC 3339 timestamp or a duration (i.e. "1h30m") to the CIM datetime
+// or interval format. Values already in the CIM format are returned unchanged
+func toCIMDateTime(s string) (string, error) {
+  if len(s) == 25 && (s[21] == '+' || s[21] == '-' || s[21] == ':') {
+    return s, nil
+  }
+  if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+    return formatCIMDateTime(t), nil
+  }
+  if d, err := time.ParseDuration(s); err == nil && d >= 0 {
+    days := int64(d / (24 * time.Hour))
+    d -= time.Duration(days) * 24 * time.Hour
+
+    return fmt.Sprintf("%08d%02d%02d%02d.%06d:000", days,
+      int64(d/time.Hour), int64(d/time.Minute)%60, int64(d/time.Second)%60, int64(d/time.Microsecond)%1000000), nil
+  }
+  return "", fmt.Errorf("invalid datetime %q: expected RFC 3339, a duration or CIM format", s)
+}
+
+func formatCIMDateTime(t time.Time) string {
+  _, offset := t.Zone()
+  return fmt.Sprintf("%s%+04d", t.Format(cimDateTimeLayout), offset/60)
+}
+
+// decodeValues returns the property values of an instance like wmio.Object.Values, with
+// CIM datetimes converted to RFC 3339 timestamps (or durations for intervals), and references
+// split into their class and keys
+func decodeValues(obj *wmio.Object) map[string]any {
+
+  values := make(map[string]any)
+
+  if obj == nil || obj.Instance == nil {
+    return values
+  }
+  for _, prop := range obj.Instance.Properties {
+    values[prop.Name] = decodeValue(prop.Value)
+  }
+  return values
+}
+
+func decodeValue(v wmio.Value) any {
+
+  switch value := v.Value.(type) {
+  case *wmio.Object:
+    return decodeValues(value)
+
+  case []*wmio.Object:
+    out := make([]any, len(value))
+    for i := range value {
+      out[i] = decodeValues(value[i])
+    }
+    return out
+
+  case string:
+    switch v.Type {
+    case wmio.DateTime:
+      return decodeDateTime(value)
+    case wmio.Ref:
+      return decodeRef(value)
+    }
+
+  case []string:
+    switch v.Type {
+    case wmio.DateTimeArray, wmio.RefArray:
+      out := make([]any, len(value))
+      for i := range value {
+        out[i] = decodeValue(wmio.Value{Type: v.Type &^ wmio.CIMArray, Value: value[i]})
+      }
+      return out
+    }
+  }
+  return v.Value
+}
+
+// decodeDateTime converts a CIM datetime (yyyymmddHHMMSS.mmmmmmsUUU) to RFC 3339,
+// or a CIM interval (ddddddddHHMMSS.mmmmmm:000) to a duration string
+func decodeDateTime(s string) any {
+  if len(s) != 25 {
+    return s
+  }
+  if s[21] == ':' {
+    var fields [5]int64
+
+    for i, f := range []string{s[:8], s[8:10], s[10:12], s[12:14], s[15:21]} {
+      n, err := strconv.ParseInt(f, 10, 64)
+      if err != nil {
+        return s
+      }
+      fields[i] = n
+    }
+    return (time.Duration(fields[0])*24*time.Hour +
+      time.Duration(fields[1])*time.Hour +
+      time.Duration(fields[2])*time.Minute +
+      time.Duration(fields[3])*time.Second +
+      time.Duration(fields[4])*time.Microsecond).String()
+  }
+  offset, err := strconv.Atoi(s[21:])
+  if err != nil {
+    return s
+  }
+  t, err := time.ParseInLocation(cimDateTimeLayout, s[:21], time.FixedZone("", offset*60))
+  if err != nil {
+    return s // Wildcard fields (i.e. "********") can't be represented
+  }
+  return t.Format(time.RFC3339Nano)
+}
+
+// decodeRef splits an object reference (i.e. \\HOST\root\cimv2:Win32_Service.Name="Spooler")
+// into the path, class and key values
+func decodeRef(path string) any {
+
+  ref := map[string]any{"path": path}
+  rel := path
+
+  if i := strings.Index(rel, ":"); i >= 0 && !strings.Contains(rel[:i], `"`) {
+    ref["namespace"], rel = rel[:i], rel[i+1:]
+  }
+  class, keys, ok := strings.Cut(rel, ".")
+
+  if !ok {
+    class, keys, _ = strings.Cut(rel, "=") // Singletons (i.e. Win32_WMISetting=@)
+    keys = ""
+  }
+  ref["class"] = class
+
+  if keys != "" {
+    kv := make(map[string]any)
+
+    for _, pair := range splitKeys(keys) {
+      if k, v, ok := strings.Cut(pair, "="); ok {
+        if uq, err := strconv.Unquote(v); err == nil {
+          kv[k] = uq
+        } else {
+          kv[k] = v
+        }
+      }
+    }
+    ref["keys"] = kv
+  }
+  return ref
+}
+
+// splitKeys splits the key bindings of an object path on commas outside of quoted values
+func splitKeys(keys string) (out []string) {
+  var quoted, escaped bool
+  start := 0
+
+  for i, c := range keys {
+    switch {
+    case escaped:
+      escaped = false
+    case c == '\\' && quoted:
+      escaped = true
+    case c == '"':
+      quoted = !quoted
+    case c == ',' && !quoted:
+      out, start = append(out, keys[start:i]), i+1
+    }
+  }
+  return append(out, keys[start:])
+}